@@ -0,0 +1,119 @@
+// Package auth finds API tokens for the services coauthors are fetched from.
+//
+// Tokens are secrets: they are never included in errors or output.
+package auth
+
+import (
+	"os"
+	"strings"
+
+	"github.com/brettbuddin/partner/internal/repository"
+)
+
+// Service is a code hosting service with an API
+type Service struct {
+	Name string
+	// env returns the environment variables that may hold a token for a
+	// host, in order of preference.
+	env func(host string) []string
+	// cliToken returns the token the service's command line client has
+	// stored for a host.
+	cliToken func(host string) (string, error)
+}
+
+// GitHub is github.com, or a GitHub Enterprise Server. As with the gh CLI,
+// GH_TOKEN and GITHUB_TOKEN are only used for github.com, and
+// GH_ENTERPRISE_TOKEN and GITHUB_ENTERPRISE_TOKEN for any other host.
+var GitHub = Service{
+	Name: "GitHub",
+	env: func(host string) []string {
+		if strings.EqualFold(host, "github.com") {
+			return []string{"GH_TOKEN", "GITHUB_TOKEN"}
+		}
+		return []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	},
+	cliToken: ghToken,
+}
+
+// GitLab is gitlab.com, or a self-managed GitLab
+var GitLab = Service{
+	Name: "GitLab",
+	env: func(string) []string {
+		return []string{"GITLAB_TOKEN"}
+	},
+	cliToken: glabToken,
+}
+
+// Bitbucket is Bitbucket Cloud, or a Bitbucket Data Center instance. It has
+// no CLI whose configuration holds tokens.
+var Bitbucket = Service{
+	Name: "Bitbucket",
+	env: func(string) []string {
+		return []string{"BITBUCKET_TOKEN"}
+	},
+	cliToken: func(string) (string, error) {
+		return "", nil
+	},
+}
+
+// Gitea is a Gitea or Forgejo instance, such as Codeberg
+var Gitea = Service{
+	Name: "Gitea",
+	env: func(string) []string {
+		return []string{"GITEA_TOKEN"}
+	},
+	cliToken: teaToken,
+}
+
+// Gerrit is a Gerrit Code Review instance. Gerrit authenticates with a
+// username and HTTP password, so the password is looked up as the token, and
+// the username comes from git's credential helpers or ~/.netrc.
+var Gerrit = Service{
+	Name: "Gerrit",
+	env: func(string) []string {
+		return []string{"GERRIT_HTTP_PASSWORD"}
+	},
+	cliToken: func(string) (string, error) {
+		return "", nil
+	},
+}
+
+// Tokens looks up API tokens for a service
+type Tokens struct {
+	Service Service
+	// Dir is the directory git credential helpers are run in
+	Dir string
+}
+
+// Token returns the API token for a host of the service. These are tried in
+// order, and the first token found is used:
+//
+//  1. The service's environment variables (e.g. GITHUB_TOKEN)
+//  2. The configuration of the service's CLI (gh, glab or tea)
+//  3. git's credential helpers, as git credential fill
+//  4. The host's machine entry in ~/.netrc, or the file named by NETRC
+//
+// An empty token is returned when none is found, and requests should be made
+// anonymously.
+func (t Tokens) Token(host string) (string, error) {
+	_, token, err := t.Login(host)
+	return token, err
+}
+
+// Login returns the username and token for a host of the service, looked up
+// in the same order as Token. The username is empty when the token's source
+// doesn't record one, as with environment variables.
+func (t Tokens) Login(host string) (username, token string, err error) {
+	for _, name := range t.Service.env(host) {
+		if token := os.Getenv(name); token != "" {
+			return "", token, nil
+		}
+	}
+	if token, err := t.Service.cliToken(host); err != nil || token != "" {
+		return "", token, err
+	}
+	if username, token := repository.Credential(t.Dir, host); token != "" {
+		return username, token, nil
+	}
+	return netrcLogin(host)
+}
@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestToken(t *testing.T) {
+	dir := newEnvironment(t)
+	tokens := Tokens{Service: GitHub, Dir: dir}
+
+	// Nothing configured
+	token, err := tokens.Token("github.com")
+	require.NoError(t, err)
+	require.Empty(t, token)
+
+	// Each source takes precedence over the ones after it
+	writeFile(t, filepath.Join(dir, ".netrc"), "machine github.com login brett password from-netrc\n")
+	token, err = tokens.Token("github.com")
+	require.NoError(t, err)
+	require.Equal(t, "from-netrc", token)
+
+	writeFile(t, filepath.Join(dir, ".gitconfig"), "[credential]\n\thelper = \"!f() { echo username=brett; echo password=from-git; }; f\"\n")
+	token, err = tokens.Token("github.com")
+	require.NoError(t, err)
+	require.Equal(t, "from-git", token)
+
+	writeFile(t, filepath.Join(dir, "gh", "hosts.yml"), "github.com:\n    user: brett\n    oauth_token: from-gh\n")
+	token, err = tokens.Token("github.com")
+	require.NoError(t, err)
+	require.Equal(t, "from-gh", token)
+
+	setenv(t, "GITHUB_TOKEN", "from-env")
+	token, err = tokens.Token("github.com")
+	require.NoError(t, err)
+	require.Equal(t, "from-env", token)
+
+	// GITHUB_TOKEN is only for github.com
+	token, err = tokens.Token("github.example.com")
+	require.NoError(t, err)
+	require.Equal(t, "from-git", token)
+	setenv(t, "GITHUB_ENTERPRISE_TOKEN", "from-enterprise-env")
+	token, err = tokens.Token("github.example.com")
+	require.NoError(t, err)
+	require.Equal(t, "from-enterprise-env", token)
+}
+
+func TestToken_GitLab(t *testing.T) {
+	dir := newEnvironment(t)
+	tokens := Tokens{Service: GitLab, Dir: dir}
+
+	writeFile(t, filepath.Join(dir, "glab-cli", "config.yml"), "hosts:\n    gitlab.example.com:\n        token: from-glab\n        api_protocol: https\n")
+	token, err := tokens.Token("gitlab.example.com")
+	require.NoError(t, err)
+	require.Equal(t, "from-glab", token)
+	token, err = tokens.Token("gitlab.com")
+	require.NoError(t, err)
+	require.Empty(t, token)
+
+	setenv(t, "GITLAB_TOKEN", "from-env")
+	token, err = tokens.Token("gitlab.example.com")
+	require.NoError(t, err)
+	require.Equal(t, "from-env", token)
+}
+
+func TestToken_Gitea(t *testing.T) {
+	dir := newEnvironment(t)
+	tokens := Tokens{Service: Gitea, Dir: dir}
+
+	writeFile(t, filepath.Join(dir, "tea", "config.yml"), "logins:\n- name: codeberg\n  url: https://codeberg.org\n  token: from-tea\n  user: brettbuddin\n")
+	token, err := tokens.Token("codeberg.org")
+	require.NoError(t, err)
+	require.Equal(t, "from-tea", token)
+	token, err = tokens.Token("gitea.example.com")
+	require.NoError(t, err)
+	require.Empty(t, token)
+}
+
+func TestToken_MalformedConfig(t *testing.T) {
+	dir := newEnvironment(t)
+	tokens := Tokens{Service: GitHub, Dir: dir}
+
+	// Errors never quote the file, which holds secrets
+	writeFile(t, filepath.Join(dir, "gh", "hosts.yml"), "github.com: [oauth_token: secret-token\n")
+	_, err := tokens.Token("github.com")
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "secret-token")
+}
+
+func TestLogin_Gerrit(t *testing.T) {
+	dir := newEnvironment(t)
+	tokens := Tokens{Service: Gerrit, Dir: dir}
+
+	writeFile(t, filepath.Join(dir, ".netrc"), "machine review.example.com password from-netrc login brett\n")
+	username, token, err := tokens.Login("review.example.com")
+	require.NoError(t, err)
+	require.Equal(t, "brett", username)
+	require.Equal(t, "from-netrc", token)
+
+	writeFile(t, filepath.Join(dir, ".gitconfig"), "[credential]\n\thelper = \"!f() { echo username=bbuddin; echo password=from-git; }; f\"\n")
+	username, token, err = tokens.Login("review.example.com")
+	require.NoError(t, err)
+	require.Equal(t, "bbuddin", username)
+	require.Equal(t, "from-git", token)
+
+	// Environment variables hold only the password
+	setenv(t, "GERRIT_HTTP_PASSWORD", "from-env")
+	username, token, err = tokens.Login("review.example.com")
+	require.NoError(t, err)
+	require.Empty(t, username)
+	require.Equal(t, "from-env", token)
+}
+
+func TestToken_CredentialPrompts(t *testing.T) {
+	dir := newEnvironment(t)
+	tokens := Tokens{Service: GitHub, Dir: dir}
+
+	// Credential helpers are told not to prompt
+	writeFile(t, filepath.Join(dir, ".gitconfig"), "[credential]\n\thelper = \"!f() { echo username=brett; echo password=$GIT_TERMINAL_PROMPT-$GCM_INTERACTIVE; }; f\"\n")
+	token, err := tokens.Token("github.com")
+	require.NoError(t, err)
+	require.Equal(t, "0-never", token)
+}
+
+func TestNetrcLogin(t *testing.T) {
+	dir := newEnvironment(t)
+
+	writeFile(t, filepath.Join(dir, ".netrc"), `machine api.github.com
+  login brett
+  password api-token
+
+macdef init
+machine github.com password not-a-password
+
+default login anonymous password default-token
+machine gitlab.com login brett password gitlab-token
+`)
+	tests := []struct {
+		host     string
+		login    string
+		password string
+	}{
+		{host: "api.github.com", login: "brett", password: "api-token"},
+		{host: "gitlab.com", login: "brett", password: "gitlab-token"},
+		// The default entry is never sent to an API
+		{host: "github.com", login: "", password: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			login, password, err := netrcLogin(tt.host)
+			require.NoError(t, err)
+			require.Equal(t, tt.login, login)
+			require.Equal(t, tt.password, password)
+		})
+	}
+}
+
+// newEnvironment keeps token lookups away from the real environment and
+// configuration files, placing them all in a temporary directory instead
+func newEnvironment(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "partner_auth_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	setenv(t, "HOME", dir)
+	setenv(t, "XDG_CONFIG_HOME", dir)
+	setenv(t, "GIT_CONFIG_NOSYSTEM", "1")
+	setenv(t, "GH_CONFIG_DIR", filepath.Join(dir, "gh"))
+	setenv(t, "GLAB_CONFIG_DIR", filepath.Join(dir, "glab-cli"))
+	setenv(t, "NETRC", filepath.Join(dir, ".netrc"))
+	for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN", "GITLAB_TOKEN", "BITBUCKET_TOKEN", "GITEA_TOKEN", "GERRIT_HTTP_PASSWORD"} {
+		setenv(t, name, "")
+	}
+	return dir
+}
+
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+
+	old, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(path, []byte(content), 0600)
+	require.NoError(t, err)
+}
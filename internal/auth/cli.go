@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atrox/homedir"
+	"gopkg.in/yaml.v3"
+)
+
+// ghToken reads the token gh has stored for a host in hosts.yml. Recent
+// versions of gh keep tokens in the system keyring instead, which isn't
+// consulted.
+func ghToken(host string) (string, error) {
+	dir, err := configDir("GH_CONFIG_DIR", "gh")
+	if err != nil {
+		return "", err
+	}
+	var hosts map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+	}
+	if err := readYAML(filepath.Join(dir, "hosts.yml"), &hosts); err != nil {
+		return "", err
+	}
+	for name, h := range hosts {
+		if strings.EqualFold(name, host) {
+			return h.OAuthToken, nil
+		}
+	}
+	return "", nil
+}
+
+// glabToken reads the token glab has stored for a host in config.yml
+func glabToken(host string) (string, error) {
+	dir, err := configDir("GLAB_CONFIG_DIR", "glab-cli")
+	if err != nil {
+		return "", err
+	}
+	var config struct {
+		Hosts map[string]struct {
+			Token string `yaml:"token"`
+		} `yaml:"hosts"`
+	}
+	if err := readYAML(filepath.Join(dir, "config.yml"), &config); err != nil {
+		return "", err
+	}
+	for name, h := range config.Hosts {
+		if strings.EqualFold(name, host) {
+			return h.Token, nil
+		}
+	}
+	return "", nil
+}
+
+// teaToken reads the token tea has stored for a host in config.yml
+func teaToken(host string) (string, error) {
+	dir, err := configDir("", "tea")
+	if err != nil {
+		return "", err
+	}
+	var config struct {
+		Logins []struct {
+			URL   string `yaml:"url"`
+			Token string `yaml:"token"`
+		} `yaml:"logins"`
+	}
+	if err := readYAML(filepath.Join(dir, "config.yml"), &config); err != nil {
+		return "", err
+	}
+	for _, login := range config.Logins {
+		if u, err := url.Parse(login.URL); err == nil && strings.EqualFold(u.Host, host) {
+			return login.Token, nil
+		}
+	}
+	return "", nil
+}
+
+// configDir returns the configuration directory of a CLI: the directory named
+// by its environment variable, if it has one, or the named directory in
+// XDG_CONFIG_HOME or ~/.config.
+func configDir(envVar, name string) (string, error) {
+	if dir := os.Getenv(envVar); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, name), nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", name), nil
+}
+
+// readYAML decodes a YAML file. A missing file is left undecoded. The
+// decoding error isn't returned, since it may quote a token.
+func readYAML(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if err := yaml.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("failed to parse %s", path)
+	}
+	return nil
+}
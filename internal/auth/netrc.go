@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atrox/homedir"
+)
+
+// netrcLogin returns the login and password for a host in ~/.netrc, or the
+// file named by the NETRC environment variable. Only a machine entry for the
+// host itself is used: the default entry is meant for whatever server a tool
+// happens to talk to, and sending its password to an API it wasn't written for
+// could leak it.
+func netrcLogin(host string) (login, password string, err error) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return "", "", err
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+
+	type entry struct {
+		machine         string
+		login, password string
+	}
+	var (
+		entries []*entry
+		current *entry
+		inMacro bool
+	)
+	for _, line := range strings.Split(string(b), "\n") {
+		// Macro definitions run until an empty line
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields); i++ {
+			switch fields[i] {
+			case "machine":
+				current = &entry{}
+				if i++; i < len(fields) {
+					current.machine = fields[i]
+				}
+				entries = append(entries, current)
+			case "default":
+				current = &entry{}
+				entries = append(entries, current)
+			case "login":
+				if i++; i < len(fields) && current != nil {
+					current.login = fields[i]
+				}
+			case "password":
+				if i++; i < len(fields) && current != nil {
+					current.password = fields[i]
+				}
+			case "macdef":
+				current, inMacro = nil, true
+				i = len(fields)
+			}
+		}
+	}
+
+	for _, e := range entries {
+		if e.machine != "" && strings.EqualFold(e.machine, host) {
+			return e.login, e.password, nil
+		}
+	}
+	return "", "", nil
+}
@@ -6,7 +6,9 @@ import (
 	"os"
 	"time"
 
+	"github.com/brettbuddin/partner/internal/auth"
 	"github.com/brettbuddin/partner/internal/command"
+	"github.com/brettbuddin/partner/internal/manifest"
 	"github.com/urfave/cli/v2"
 )
 
@@ -20,11 +22,16 @@ func Main() {
 	app := cli.NewApp()
 	app.Name = "partner"
 	app.Usage = "Manage git coauthors"
+	app.Version = command.Version()
 	app.Commands = []*cli.Command{
 		cmdManifest(pwd),
 		cmdStatus(pwd),
 		cmdSet(pwd),
 		cmdClear(pwd),
+		cmdAmend(pwd),
+		cmdRewrite(pwd),
+		cmdHook(pwd),
+		cmdBranch(pwd),
 	}
 
 	if err = app.Run(os.Args); err != nil {
@@ -57,9 +64,20 @@ func cmdManifest(pwd string) *cli.Command {
 		Subcommands: []*cli.Command{
 			cmdManifestGitHubAdd(pwd),
 			cmdManifestGitLabAdd(pwd),
+			cmdManifestBitbucketAdd(pwd),
+			cmdManifestGiteaAdd(pwd),
+			cmdManifestGerritAdd(pwd),
 			cmdManifestAdd(pwd),
 			cmdManifestList(pwd),
 			cmdManifestRemove(pwd),
+			cmdManifestEdit(pwd),
+			cmdManifestAlias(pwd),
+			cmdManifestUnalias(pwd),
+			cmdManifestRefresh(pwd),
+			cmdManifestImportGit(pwd),
+			cmdManifestGroup(pwd),
+			cmdManifestIdentity(pwd),
+			cmdManifestMigrate(pwd),
 		},
 	}
 }
@@ -70,17 +88,28 @@ func cmdManifestGitHubAdd(pwd string) *cli.Command {
 		Aliases:   []string{"gh-add"},
 		Usage:     "Add a coauthor from GitHub usernames",
 		ArgsUsage: "[username, ...]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "host",
+				Usage: "Host of a GitHub Enterprise Server instance (default: partner.githubHost git configuration, or github.com)",
+			},
+		},
 		Action: func(c *cli.Context) error {
 			if c.Args().Len() == 0 {
 				cli.ShowCommandHelp(c, c.Command.Name)
 				return newCodeError(fmt.Errorf("at least one GitHub username is required"), 2)
 			}
-			fetcher := &command.GitHubFetcher{
-				BaseURL: "https://api.github.com",
-				Client: &http.Client{
-					Timeout: 10 * time.Second,
-				},
+			host := c.String("host")
+			if host == "" {
+				var err error
+				if host, err = command.DefaultHost(pwd, manifest.CoauthorTypeGitHub); err != nil {
+					return newCodeError(err, 1)
+				}
 			}
+			fetcher := command.NewGitHubFetcher(&http.Client{
+				Timeout: 10 * time.Second,
+			}, host)
+			fetcher.Tokens = auth.Tokens{Service: auth.GitHub, Dir: pwd}
 			paths, err := command.DefaultPaths(pwd)
 			if err != nil {
 				return newCodeError(err, 1)
@@ -99,17 +128,179 @@ func cmdManifestGitLabAdd(pwd string) *cli.Command {
 		Aliases:   []string{"gl-add"},
 		Usage:     "Add a coauthor from GitLab usernames",
 		ArgsUsage: "[username, ...]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "host",
+				Usage: "Host of a self-managed GitLab instance (default: partner.gitlabHost git configuration, or gitlab.com)",
+			},
+		},
 		Action: func(c *cli.Context) error {
 			if c.Args().Len() == 0 {
 				cli.ShowCommandHelp(c, c.Command.Name)
 				return newCodeError(fmt.Errorf("at least one GitLab username is required"), 2)
 			}
-			fetcher := &command.GitLabFetcher{
-				BaseURL: "https://gitlab.com",
-				Client: &http.Client{
-					Timeout: 10 * time.Second,
-				},
+			host := c.String("host")
+			if host == "" {
+				var err error
+				if host, err = command.DefaultHost(pwd, manifest.CoauthorTypeGitLab); err != nil {
+					return newCodeError(err, 1)
+				}
 			}
+			fetcher := command.NewGitLabFetcher(&http.Client{
+				Timeout: 10 * time.Second,
+			}, host)
+			fetcher.Tokens = auth.Tokens{Service: auth.GitLab, Dir: pwd}
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			err = command.New(paths).ManifestFetchAdd(fetcher, c.Args().Slice()...)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+func cmdManifestBitbucketAdd(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:      "bitbucket-add",
+		Aliases:   []string{"bb-add"},
+		Usage:     "Add a coauthor from Bitbucket Cloud UUIDs or account IDs, or Bitbucket Data Center usernames",
+		ArgsUsage: "[user, ...]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "host",
+				Usage: "Host of a Bitbucket Data Center instance (default: partner.bitbucketHost git configuration, or bitbucket.org)",
+			},
+			&cli.StringFlag{
+				Name:  "email",
+				Usage: "Email address to credit the coauthor with (required for Bitbucket Cloud, which doesn't share it)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() == 0 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("at least one Bitbucket user is required"), 2)
+			}
+			if c.String("email") != "" && c.Args().Len() > 1 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("--email can only be given for a single username"), 2)
+			}
+			host := c.String("host")
+			if host == "" {
+				var err error
+				if host, err = command.DefaultHost(pwd, manifest.CoauthorTypeBitbucket); err != nil {
+					return newCodeError(err, 1)
+				}
+			}
+			fetcher := command.NewBitbucketFetcher(&http.Client{
+				Timeout: 10 * time.Second,
+			}, host)
+			fetcher.Logins = auth.Tokens{Service: auth.Bitbucket, Dir: pwd}
+			fetcher.Email = c.String("email")
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			err = command.New(paths).ManifestFetchAdd(fetcher, c.Args().Slice()...)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+func cmdManifestGiteaAdd(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:      "gitea-add",
+		Usage:     "Add a coauthor from Gitea or Forgejo usernames",
+		ArgsUsage: "[username, ...]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "host",
+				Usage: "Host of a Gitea or Forgejo instance (default: partner.giteaHost git configuration, or codeberg.org)",
+			},
+			&cli.StringFlag{
+				Name:  "noreply-domain",
+				Usage: "Domain of the instance's noreply email addresses (default: partner.giteaNoreplyDomain git configuration, or noreply.<host>)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() == 0 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("at least one Gitea username is required"), 2)
+			}
+			host, domain := c.String("host"), c.String("noreply-domain")
+			var err error
+			if host == "" {
+				if host, err = command.DefaultHost(pwd, manifest.CoauthorTypeGitea); err != nil {
+					return newCodeError(err, 1)
+				}
+			}
+			if domain == "" {
+				if domain, err = command.DefaultGiteaNoreplyDomain(pwd); err != nil {
+					return newCodeError(err, 1)
+				}
+			}
+			fetcher := command.NewGiteaFetcher(&http.Client{
+				Timeout: 10 * time.Second,
+			}, host)
+			fetcher.Tokens = auth.Tokens{Service: auth.Gitea, Dir: pwd}
+			fetcher.NoreplyDomain = domain
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			err = command.New(paths).ManifestFetchAdd(fetcher, c.Args().Slice()...)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+func cmdManifestGerritAdd(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:      "gerrit-add",
+		Usage:     "Add a coauthor from Gerrit usernames",
+		ArgsUsage: "[username, ...]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "host",
+				Usage: "Host of a Gerrit instance (default: partner.gerritHost git configuration)",
+			},
+			&cli.StringFlag{
+				Name:  "username",
+				Usage: "Your Gerrit username, to authenticate with (default: partner.gerritUsername git configuration, or your git credentials)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() == 0 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("at least one Gerrit username is required"), 2)
+			}
+			host, username := c.String("host"), c.String("username")
+			var err error
+			if host == "" {
+				if host, err = command.DefaultHost(pwd, manifest.CoauthorTypeGerrit); err != nil {
+					return newCodeError(err, 1)
+				}
+			}
+			if host == "" {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("a Gerrit host is required; pass --host or set partner.gerritHost"), 2)
+			}
+			if username == "" {
+				if username, err = command.DefaultGerritUsername(pwd); err != nil {
+					return newCodeError(err, 1)
+				}
+			}
+			fetcher := command.NewGerritFetcher(&http.Client{
+				Timeout: 10 * time.Second,
+			}, host)
+			fetcher.Logins = auth.Tokens{Service: auth.Gerrit, Dir: pwd}
+			fetcher.Username = username
 			paths, err := command.DefaultPaths(pwd)
 			if err != nil {
 				return newCodeError(err, 1)
@@ -168,12 +359,71 @@ func cmdManifestList(pwd string) *cli.Command {
 		Name:    "list",
 		Aliases: []string{"ls"},
 		Usage:   "List coauthors",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "source",
+				Usage: "Show which manifest each coauthor comes from",
+			},
+			&cli.BoolFlag{
+				Name:    "quiet",
+				Aliases: []string{"q"},
+				Usage:   "Only list IDs and aliases",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			cmd := command.New(paths)
+			if c.Bool("quiet") {
+				err = cmd.ManifestListIDs(os.Stdout)
+			} else {
+				err = cmd.ManifestList(os.Stdout, c.Bool("source"))
+			}
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+func cmdManifestAlias(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:      "alias",
+		Usage:     "Give a coauthor shorter names",
+		ArgsUsage: "<id> [alias, ...]",
 		Action: func(c *cli.Context) error {
+			if c.Args().Len() < 2 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("an ID and at least one alias are required"), 2)
+			}
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			if err := command.New(paths).ManifestAlias(c.Args().First(), c.Args().Tail()...); err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+func cmdManifestUnalias(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:      "unalias",
+		Usage:     "Remove aliases",
+		ArgsUsage: "[alias, ...]",
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() == 0 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("at least one alias is required"), 2)
+			}
 			paths, err := command.DefaultPaths(pwd)
 			if err != nil {
 				return newCodeError(err, 1)
 			}
-			if err := command.New(paths).ManifestList(os.Stdout); err != nil {
+			if err := command.New(paths).ManifestUnalias(c.Args().Slice()...); err != nil {
 				return newCodeError(err, 1)
 			}
 			return nil
@@ -195,7 +445,333 @@ func cmdManifestRemove(pwd string) *cli.Command {
 			if err != nil {
 				return newCodeError(err, 1)
 			}
-			if err := command.New(paths).ManifestRemove(c.Args().Slice()...); err != nil {
+			if err := command.New(paths).ManifestRemove(os.Stdout, c.Args().Slice()...); err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdManifestEdit(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:      "edit",
+		Usage:     "Change a coauthor's ID, name or email address",
+		ArgsUsage: "<id>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "id",
+				Usage: "New identifier for referring to the coauthor",
+			},
+			&cli.StringFlag{
+				Name:  "email",
+				Usage: "New email address",
+			},
+			&cli.StringFlag{
+				Name:  "name",
+				Usage: "New full name",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() != 1 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("exactly one ID is required"), 2)
+			}
+			if !c.IsSet("id") && !c.IsSet("name") && !c.IsSet("email") {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("at least one of --id, --name or --email is required"), 2)
+			}
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			err = command.New(paths).ManifestEdit(c.Args().First(), manifest.Edit{
+				ID:    c.String("id"),
+				Name:  c.String("name"),
+				Email: c.String("email"),
+			})
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdManifestRefresh(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:      "refresh",
+		Usage:     "Update coauthors added from GitHub, GitLab or Gitea with their current details",
+		ArgsUsage: "[id, ...]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Usage:   "Apply changes without asking for confirmation",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			client := &http.Client{
+				Timeout: 10 * time.Second,
+			}
+			github := command.NewGitHubFetcher(client, "")
+			github.Tokens = auth.Tokens{Service: auth.GitHub, Dir: pwd}
+			gitlab := command.NewGitLabFetcher(client, "")
+			gitlab.Tokens = auth.Tokens{Service: auth.GitLab, Dir: pwd}
+			gitea := command.NewGiteaFetcher(client, "")
+			gitea.Tokens = auth.Tokens{Service: auth.Gitea, Dir: pwd}
+			refreshers := map[string]command.UserRefresher{
+				manifest.CoauthorTypeGitHub: github,
+				manifest.CoauthorTypeGitLab: gitlab,
+				manifest.CoauthorTypeGitea:  gitea,
+			}
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			err = command.New(paths).ManifestRefresh(os.Stdin, os.Stdout, c.Bool("yes"), refreshers, c.Args().Slice()...)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdManifestImportGit(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:  "import-git",
+		Usage: "Add coauthors from the current repository's history",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "since",
+				Usage: "Only look at commits more recent than a date (e.g. 6.months)",
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Usage:   "Add everyone found without asking for confirmation",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			err = command.New(paths).ManifestImportGit(os.Stdin, os.Stdout, c.Bool("yes"), c.String("since"))
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdManifestGroup(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:  "group",
+		Usage: "Coauthor group operations. Groups are activated with @name",
+		Subcommands: []*cli.Command{
+			cmdManifestGroupAdd(pwd),
+			cmdManifestGroupSet(pwd),
+			cmdManifestGroupList(pwd),
+			cmdManifestGroupRemove(pwd),
+		},
+	}
+}
+
+func cmdManifestGroupAdd(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:      "add",
+		Usage:     "Add coauthors to a group, creating it if necessary",
+		ArgsUsage: "<group> [id, ...]",
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() < 2 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("a group and at least one ID are required"), 2)
+			}
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			if err := command.New(paths).ManifestGroupAdd(c.Args().First(), c.Args().Tail()...); err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdManifestGroupSet(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:      "set",
+		Usage:     "Replace the members of a group, creating it if necessary",
+		ArgsUsage: "<group> [id, ...]",
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() < 2 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("a group and at least one ID are required"), 2)
+			}
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			if err := command.New(paths).ManifestGroupSet(c.Args().First(), c.Args().Tail()...); err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdManifestGroupList(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:    "list",
+		Aliases: []string{"ls"},
+		Usage:   "List groups",
+		Action: func(c *cli.Context) error {
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			if err := command.New(paths).ManifestGroupList(os.Stdout); err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdManifestGroupRemove(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:      "remove",
+		Aliases:   []string{"rm"},
+		Usage:     "Remove coauthors from a group, or the whole group when no IDs are given",
+		ArgsUsage: "<group> [id, ...]",
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() == 0 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("a group is required"), 2)
+			}
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			if err := command.New(paths).ManifestGroupRemove(c.Args().First(), c.Args().Tail()...); err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdManifestIdentity(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:  "identity",
+		Usage: "Coauthor identity operations. Identities are additional email addresses, chosen per repository",
+		Subcommands: []*cli.Command{
+			cmdManifestIdentityAdd(pwd),
+			cmdManifestIdentityList(pwd),
+			cmdManifestIdentityRemove(pwd),
+		},
+	}
+}
+
+func cmdManifestIdentityAdd(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:      "add",
+		Usage:     "Add an email address to a coauthor",
+		ArgsUsage: "<id> <email>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "label",
+				Usage: "Name for selecting the identity with the partner.identity git configuration",
+			},
+			&cli.StringSliceFlag{
+				Name:  "host",
+				Usage: "Remote host pattern (e.g. gitlab.example.com or *.corp.example.com) of repositories to use the identity in",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() != 2 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("an ID and an email address are required"), 2)
+			}
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			err = command.New(paths).ManifestIdentityAdd(c.Args().First(), manifest.Identity{
+				Label: c.String("label"),
+				Email: c.Args().Get(1),
+				Hosts: c.StringSlice("host"),
+			})
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdManifestIdentityList(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:      "list",
+		Aliases:   []string{"ls"},
+		Usage:     "List coauthors' email addresses, marking the ones credited in this repository",
+		ArgsUsage: "[id, ...]",
+		Action: func(c *cli.Context) error {
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			if err := command.New(paths).ManifestIdentityList(os.Stdout, c.Args().Slice()...); err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdManifestIdentityRemove(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:      "remove",
+		Aliases:   []string{"rm"},
+		Usage:     "Remove an email address from a coauthor",
+		ArgsUsage: "<id> <email or label>",
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() != 2 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("an ID and an email address or label are required"), 2)
+			}
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			if err := command.New(paths).ManifestIdentityRemove(c.Args().First(), c.Args().Get(1)); err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdManifestMigrate(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "Upgrade manifests written by older versions of partner",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "check",
+				Usage: "Exit with an error instead of migrating if any manifest is out of date",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			if err := command.New(paths).ManifestMigrate(os.Stdout, c.Bool("check")); err != nil {
 				return newCodeError(err, 1)
 			}
 			return nil
@@ -225,7 +801,21 @@ func cmdSet(pwd string) *cli.Command {
 		Name:      "set",
 		Aliases:   []string{"activate"},
 		Usage:     "Set active coauthors",
-		ArgsUsage: "[id, ...]",
+		ArgsUsage: "[id or @group, ...]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "global",
+				Usage: "Activate for every repository",
+			},
+			&cli.BoolFlag{
+				Name:  "branch",
+				Usage: "Activate only while the current branch is checked out",
+			},
+			&cli.DurationFlag{
+				Name:  "for",
+				Usage: "Deactivate after a duration (e.g. 4h). Defaults to the partner.ttl git configuration. Not available with --global",
+			},
+		},
 		Action: func(c *cli.Context) error {
 			if c.Args().Len() == 0 {
 				cli.ShowCommandHelp(c, c.Command.Name)
@@ -235,7 +825,22 @@ func cmdSet(pwd string) *cli.Command {
 			if err != nil {
 				return newCodeError(err, 1)
 			}
-			if err := command.New(paths).TemplateSet(c.Args().Slice()...); err != nil {
+			cmd := command.New(paths)
+			switch {
+			case c.Bool("global") && c.Bool("branch"):
+				return newCodeError(fmt.Errorf("--global and --branch cannot be combined"), 2)
+			case c.Bool("branch") && c.IsSet("for"):
+				return newCodeError(fmt.Errorf("coauthors linked to a branch do not expire"), 2)
+			case c.Bool("global") && c.IsSet("for"):
+				return newCodeError(fmt.Errorf("coauthors activated globally do not expire, since no hook can remove them from the global template"), 2)
+			case c.Bool("global"):
+				err = cmd.TemplateSetGlobal(c.Args().Slice()...)
+			case c.Bool("branch"):
+				err = cmd.TemplateSetBranch(c.Args().Slice()...)
+			default:
+				err = cmd.TemplateSetFor(os.Stdout, c.Duration("for"), c.Args().Slice()...)
+			}
+			if err != nil {
 				return newCodeError(err, 1)
 			}
 			return nil
@@ -247,15 +852,252 @@ func cmdClear(pwd string) *cli.Command {
 	return &cli.Command{
 		Name:  "clear",
 		Usage: "Clear active coauthors",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "global",
+				Usage: "Clear coauthors activated for every repository",
+			},
+			&cli.BoolFlag{
+				Name:  "branch",
+				Usage: "Clear coauthors linked to the current branch",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			cmd := command.New(paths)
+			switch {
+			case c.Bool("global") && c.Bool("branch"):
+				return newCodeError(fmt.Errorf("--global and --branch cannot be combined"), 2)
+			case c.Bool("global"):
+				err = cmd.TemplateClearGlobal()
+			case c.Bool("branch"):
+				err = cmd.TemplateClearBranch()
+			default:
+				err = cmd.TemplateClear()
+			}
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdAmend(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:  "amend",
+		Usage: "Add coauthors to the HEAD commit",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "ids",
+				Usage: "Coauthors to add instead of the active ones",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Amend even if the commit has already been pushed",
+			},
+		},
 		Action: func(c *cli.Context) error {
 			paths, err := command.DefaultPaths(pwd)
 			if err != nil {
 				return newCodeError(err, 1)
 			}
-			if err := command.New(paths).TemplateClear(); err != nil {
+			if err := command.New(paths).Amend(c.Bool("force"), c.StringSlice("ids")...); err != nil {
 				return newCodeError(err, 1)
 			}
 			return nil
 		},
 	}
 }
+
+func cmdRewrite(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:      "rewrite",
+		Usage:     "Add coauthors to every commit in a range",
+		ArgsUsage: "<base>..<head>",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "ids",
+				Usage: "Coauthors to add instead of the active ones",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "List the commits that would change without rewriting them",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Rewrite even if the commits have already been pushed",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() != 1 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("a commit range is required"), 2)
+			}
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			err = command.New(paths).Rewrite(os.Stdout, c.Args().First(), c.Bool("dry-run"), c.Bool("force"), c.StringSlice("ids")...)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdBranch(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:  "branch",
+		Usage: "Branch rule operations",
+		Subcommands: []*cli.Command{
+			cmdBranchExclude(pwd),
+			cmdBranchInclude(pwd),
+		},
+	}
+}
+
+func cmdBranchExclude(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:      "exclude",
+		Usage:     "Never add coauthors on branches matching patterns",
+		ArgsUsage: "[pattern, ...]",
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() == 0 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("at least one branch pattern is required"), 2)
+			}
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			if err := command.New(paths).BranchExclude(c.Args().Slice()...); err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdBranchInclude(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:      "include",
+		Usage:     "Remove patterns added with exclude",
+		ArgsUsage: "[pattern, ...]",
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() == 0 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("at least one branch pattern is required"), 2)
+			}
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			if err := command.New(paths).BranchInclude(c.Args().Slice()...); err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdHook(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:  "hook",
+		Usage: "Git hook operations",
+		Subcommands: []*cli.Command{
+			cmdHookInstall(pwd),
+			cmdHookUninstall(pwd),
+			cmdHookStatus(pwd),
+			cmdHookRun(pwd),
+		},
+	}
+}
+
+func cmdHookInstall(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:  "install",
+		Usage: "Install git hooks that add active coauthors to every commit",
+		Action: func(c *cli.Context) error {
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			if err := command.New(paths).HookInstall(); err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdHookUninstall(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:  "uninstall",
+		Usage: "Remove git hooks installed by partner",
+		Action: func(c *cli.Context) error {
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			if err := command.New(paths).HookUninstall(); err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdHookStatus(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:  "status",
+		Usage: "Show which git hooks are installed and where",
+		Action: func(c *cli.Context) error {
+			paths, err := command.DefaultPaths(pwd)
+			if err != nil {
+				return newCodeError(err, 1)
+			}
+			if err := command.New(paths).HookStatus(os.Stdout); err != nil {
+				return newCodeError(err, 1)
+			}
+			return nil
+		},
+	}
+}
+
+func cmdHookRun(pwd string) *cli.Command {
+	return &cli.Command{
+		Name:      "run",
+		Usage:     "Run a git hook (invoked by the installed hook scripts)",
+		ArgsUsage: "[hook] [args, ...]",
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() == 0 {
+				cli.ShowCommandHelp(c, c.Command.Name)
+				return newCodeError(fmt.Errorf("a hook name is required"), 2)
+			}
+			args := c.Args().Tail()
+			switch hook := c.Args().First(); hook {
+			case command.HookPrepareCommitMsg:
+				if len(args) == 0 {
+					return newCodeError(fmt.Errorf("%s requires a commit message file", hook), 2)
+				}
+			default:
+				return newCodeError(fmt.Errorf("unsupported hook %q", hook), 2)
+			}
+			// A failing hook aborts the commit. Coauthors are never worth
+			// that, so errors are only reported.
+			paths, err := command.DefaultPaths(pwd)
+			if err == nil {
+				err = command.New(paths).HookPrepareCommitMsg(args[0])
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "partner: %s; coauthors were not added to the commit\n", err)
+			}
+			return nil
+		},
+	}
+}
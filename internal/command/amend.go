@@ -0,0 +1,78 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/brettbuddin/partner/internal/repository"
+	"github.com/brettbuddin/partner/internal/trailer"
+)
+
+// Amend adds coauthors to the HEAD commit. The active coauthors are used
+// unless IDs are given. The commit's author and author date are kept. Commits
+// that have already been pushed are refused unless force is set.
+func (c *Command) Amend(force bool, ids ...string) error {
+	repoPaths, err := c.Paths.Repository()
+	if err != nil {
+		return err
+	}
+	coauthors, err := c.coauthorsOrActive(ids...)
+	if err != nil {
+		return err
+	}
+
+	if !force {
+		remotes, err := repository.RemoteRefsContaining(repoPaths.Root, "HEAD")
+		if err != nil {
+			return err
+		}
+		if len(remotes) > 0 {
+			return fmt.Errorf("HEAD has already been pushed to %s (use --force to amend anyway)", strings.Join(remotes, ", "))
+		}
+	}
+
+	commit, err := repository.ReadCommit(repoPaths.Root, "HEAD")
+	if err != nil {
+		return err
+	}
+	msg, err := trailer.AppendCoauthors(repoPaths.Root, []byte(commit.Message), coauthors)
+	if err != nil {
+		return err
+	}
+	if string(msg) == commit.Message {
+		return nil
+	}
+	commit.Message = string(msg)
+
+	amended, err := repository.WriteCommit(repoPaths.Root, commit)
+	if err != nil {
+		return err
+	}
+	return repository.UpdateRef(repoPaths.Root, "HEAD", amended, commit.Hash, "partner: amend coauthors")
+}
+
+// coauthorsOrActive looks up coauthors by ID, or returns the active coauthors
+// if no IDs are given
+func (c *Command) coauthorsOrActive(ids ...string) ([]manifest.Coauthor, error) {
+	if len(ids) == 0 {
+		set, err := c.activeSet()
+		if err != nil {
+			return nil, err
+		}
+		ids = set.IDs
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no coauthors are active")
+	}
+
+	m, err := c.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+	coauthors, err := m.Find(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return selectIdentities(c.Paths.WorkDir, coauthors)
+}
@@ -0,0 +1,73 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmend(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	dir := cmd.Paths.WorkDir
+
+	commitAs(t, dir, "Add the thing\n\nSigned-off-by: Brett Buddin <brett@buddin.org>")
+	authorBefore := runGit(t, dir, "log", "-1", "--format=%an <%ae> %ad")
+
+	err := cmd.ManifestAdd("persona", "Person A", "a@buddin.org")
+	require.NoError(t, err)
+	err = cmd.ManifestAdd("personb", "Person B", "b@buddin.org")
+	require.NoError(t, err)
+
+	// Nothing is active yet
+	err = cmd.Amend(false)
+	require.Error(t, err)
+
+	err = cmd.TemplateSet("persona")
+	require.NoError(t, err)
+	err = cmd.Amend(false)
+	require.NoError(t, err)
+
+	require.Equal(t, `Add the thing
+
+Signed-off-by: Brett Buddin <brett@buddin.org>
+Co-Authored-By: Person A <a@buddin.org>`, runGit(t, dir, "log", "-1", "--format=%B"))
+	require.Equal(t, authorBefore, runGit(t, dir, "log", "-1", "--format=%an <%ae> %ad"))
+	require.Equal(t, "1", runGit(t, dir, "rev-list", "--count", "HEAD"))
+
+	// Explicit IDs, skipping those already credited
+	err = cmd.Amend(false, "persona", "personb")
+	require.NoError(t, err)
+	require.Equal(t, `Add the thing
+
+Signed-off-by: Brett Buddin <brett@buddin.org>
+Co-Authored-By: Person A <a@buddin.org>
+Co-Authored-By: Person B <b@buddin.org>`, runGit(t, dir, "log", "-1", "--format=%B"))
+}
+
+func TestAmend_Pushed(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	dir := cmd.Paths.WorkDir
+
+	commitAs(t, dir, "Add the thing")
+	runGit(t, dir, "update-ref", "refs/remotes/origin/master", "HEAD")
+
+	err := cmd.ManifestAdd("persona", "Person A", "a@buddin.org")
+	require.NoError(t, err)
+
+	err = cmd.Amend(false, "persona")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "origin/master")
+	require.Equal(t, "Add the thing", runGit(t, dir, "log", "-1", "--format=%B"))
+
+	err = cmd.Amend(true, "persona")
+	require.NoError(t, err)
+	require.Contains(t, runGit(t, dir, "log", "-1", "--format=%B"), "Person A")
+}
+
+// commitAs makes an empty commit with a fixed author and date
+func commitAs(t *testing.T, dir, message string) {
+	t.Helper()
+	runGit(t, dir, "config", "user.name", "Brett Buddin")
+	runGit(t, dir, "config", "user.email", "brett@buddin.org")
+	runGit(t, dir, "commit", "--allow-empty", "--date=2021-01-04T09:00:00Z", "-m", message)
+}
@@ -0,0 +1,182 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+)
+
+// bitbucketHost is the host of Bitbucket Cloud. Any other host is a Bitbucket
+// Data Center (or Server) instance.
+const bitbucketHost = "bitbucket.org"
+
+var (
+	// bitbucketUUID matches the UUID of a Bitbucket Cloud user, with or
+	// without its braces
+	bitbucketUUID = regexp.MustCompile(`^\{?([0-9a-fA-F]{8}-(?:[0-9a-fA-F]{4}-){3}[0-9a-fA-F]{12})\}?$`)
+	// bitbucketAccountID matches the Atlassian account ID of a Bitbucket
+	// Cloud user
+	bitbucketAccountID = regexp.MustCompile(`^(?:\d+:[0-9a-fA-F-]+|[0-9a-fA-F]{24})$`)
+)
+
+// BitbucketFetcher fetches coauthors from Bitbucket Cloud, or from a Bitbucket
+// Data Center instance.
+type BitbucketFetcher struct {
+	Client  *http.Client
+	BaseURL string
+	// Host is the Bitbucket instance coauthors are fetched from.
+	// bitbucket.org is assumed when empty.
+	Host string
+	// Logins authenticates requests, if set. App passwords and account
+	// passwords are sent with their username, and access tokens without one.
+	Logins LoginSource
+	// Email is the address to credit coauthors with. Bitbucket Cloud doesn't
+	// share users' email addresses, so it must be given for them.
+	Email string
+}
+
+// NewBitbucketFetcher returns a fetcher for the Bitbucket instance at host.
+// An empty host is Bitbucket Cloud.
+func NewBitbucketFetcher(client *http.Client, host string) *BitbucketFetcher {
+	if host == "" || strings.EqualFold(host, bitbucketHost) {
+		return &BitbucketFetcher{Client: client, BaseURL: "https://api.bitbucket.org", Host: bitbucketHost}
+	}
+	return &BitbucketFetcher{Client: client, BaseURL: "https://" + host, Host: host}
+}
+
+func (f *BitbucketFetcher) host() string {
+	if f.Host == "" {
+		return bitbucketHost
+	}
+	return strings.ToLower(f.Host)
+}
+
+func (f *BitbucketFetcher) Fetch(username string) (manifest.Coauthor, error) {
+	if f.host() == bitbucketHost {
+		return f.fetchCloud(username)
+	}
+	return f.fetchDataCenter(username)
+}
+
+// fetchCloud looks up a Bitbucket Cloud user by their UUID or account ID.
+// Bitbucket Cloud no longer looks users up by nickname, and nicknames aren't
+// unique, so the account ID is the coauthor's ID and the nickname is suggested
+// as an alias.
+func (f *BitbucketFetcher) fetchCloud(username string) (manifest.Coauthor, error) {
+	selected := username
+	if m := bitbucketUUID.FindStringSubmatch(username); m != nil {
+		selected = "{" + m[1] + "}"
+	} else if !bitbucketAccountID.MatchString(username) {
+		return manifest.Coauthor{}, fmt.Errorf("Bitbucket Cloud looks users up by UUID or account ID, not %q; find them on the user's profile", username)
+	}
+	if f.Email == "" {
+		return manifest.Coauthor{}, fmt.Errorf("Bitbucket Cloud doesn't share the email address of %q; give the address to credit them with", username)
+	}
+	var user struct {
+		AccountID   string `json:"account_id"`
+		Nickname    string `json:"nickname"`
+		DisplayName string `json:"display_name"`
+	}
+	err := f.get(fmt.Sprintf("%s/2.0/users/%s", f.BaseURL, url.PathEscape(selected)), username, &user, func(body []byte) string {
+		var cloudError struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(body, &cloudError)
+		return cloudError.Error.Message
+	})
+	if err != nil {
+		return manifest.Coauthor{}, err
+	}
+	ca := manifest.Coauthor{
+		Email: f.Email,
+		ID:    user.AccountID,
+		Name:  user.DisplayName,
+		Type:  manifest.CoauthorTypeBitbucket,
+		Host:  f.host(),
+	}
+	if user.Nickname != "" {
+		ca.Aliases = []string{user.Nickname}
+	}
+	return ca, nil
+}
+
+func (f *BitbucketFetcher) fetchDataCenter(username string) (manifest.Coauthor, error) {
+	var user struct {
+		Name         string `json:"name"`
+		DisplayName  string `json:"displayName"`
+		EmailAddress string `json:"emailAddress"`
+	}
+	err := f.get(fmt.Sprintf("%s/rest/api/1.0/users/%s", f.BaseURL, url.PathEscape(username)), username, &user, func(body []byte) string {
+		var dcError struct {
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		json.Unmarshal(body, &dcError)
+		if len(dcError.Errors) == 0 {
+			return ""
+		}
+		return dcError.Errors[0].Message
+	})
+	if err != nil {
+		return manifest.Coauthor{}, err
+	}
+	email := user.EmailAddress
+	if f.Email != "" {
+		email = f.Email
+	}
+	if email == "" {
+		return manifest.Coauthor{}, fmt.Errorf("%s doesn't share the email address of %q; give the address to credit them with", f.host(), username)
+	}
+	return manifest.Coauthor{
+		Email: email,
+		ID:    user.Name,
+		Name:  user.DisplayName,
+		Type:  manifest.CoauthorTypeBitbucket,
+		Host:  f.host(),
+	}, nil
+}
+
+// get decodes a response from the Bitbucket API. The two APIs report errors
+// differently, so errorMessage extracts the message from an error response.
+func (f *BitbucketFetcher) get(rawURL, username string, v interface{}, errorMessage func([]byte) string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	r, err := http.NewRequest(http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return err
+	}
+	if err := authorizeLogin(r, f.Logins, f.host()); err != nil {
+		return err
+	}
+	resp, err := f.Client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body json.RawMessage
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return err
+		}
+		message := errorMessage(body)
+		if message == "" {
+			message = resp.Status
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return UserNotFoundError{Username: username, Service: "Bitbucket", Message: message}
+		}
+		return fmt.Errorf("error fetching %q from Bitbucket: %s", username, message)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
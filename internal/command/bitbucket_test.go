@@ -0,0 +1,140 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBitbucketFetcher_Cloud(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/2.0/users/{2b3a7f1e-6c0d-4a8e-9f3b-5d1c2e4a6b8c}", "/2.0/users/557058:5a3c1e2f-8b7d-4c6a-9e1f-0d2b3c4a5e6f":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"type": "error", "error": {"message": "User not found"}}`)
+			return
+		}
+		f, err := os.Open("testdata/bitbucket_cloud_user.json")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		io.Copy(w, f)
+	}))
+	defer server.Close()
+
+	f := BitbucketFetcher{
+		Client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		BaseURL: server.URL,
+	}
+
+	// Bitbucket Cloud doesn't share email addresses
+	_, err := f.Fetch("{2b3a7f1e-6c0d-4a8e-9f3b-5d1c2e4a6b8c}")
+	require.EqualError(t, err, `Bitbucket Cloud doesn't share the email address of "{2b3a7f1e-6c0d-4a8e-9f3b-5d1c2e4a6b8c}"; give the address to credit them with`)
+
+	// nor look users up by nickname
+	f.Email = "brett@buddin.org"
+	_, err = f.Fetch("brettbuddin")
+	require.EqualError(t, err, `Bitbucket Cloud looks users up by UUID or account ID, not "brettbuddin"; find them on the user's profile`)
+
+	// The account ID identifies the coauthor, whichever way they're looked up
+	expected := manifest.Coauthor{
+		ID:      "557058:5a3c1e2f-8b7d-4c6a-9e1f-0d2b3c4a5e6f",
+		Name:    "Brett Buddin",
+		Email:   "brett@buddin.org",
+		Type:    manifest.CoauthorTypeBitbucket,
+		Host:    "bitbucket.org",
+		Aliases: []string{"brettbuddin"},
+	}
+	for _, user := range []string{
+		"{2b3a7f1e-6c0d-4a8e-9f3b-5d1c2e4a6b8c}",
+		"2b3a7f1e-6c0d-4a8e-9f3b-5d1c2e4a6b8c",
+		"557058:5a3c1e2f-8b7d-4c6a-9e1f-0d2b3c4a5e6f",
+	} {
+		ca, err := f.Fetch(user)
+		require.NoError(t, err)
+		require.Equal(t, expected, ca)
+	}
+
+	_, err = f.Fetch("{00000000-0000-0000-0000-000000000000}")
+	var notFound UserNotFoundError
+	require.True(t, errors.As(err, &notFound))
+	require.Equal(t, "User not found", notFound.Message)
+
+	// App passwords are sent with their username, and access tokens alone
+	f.Logins = logins{"bitbucket.org": {"brettbuddin", "app-password"}}
+	_, err = f.Fetch("557058:5a3c1e2f-8b7d-4c6a-9e1f-0d2b3c4a5e6f")
+	require.NoError(t, err)
+	require.Equal(t, "Basic YnJldHRidWRkaW46YXBwLXBhc3N3b3Jk", authorization)
+	f.Logins = logins{"bitbucket.org": {"", "access-token"}}
+	_, err = f.Fetch("557058:5a3c1e2f-8b7d-4c6a-9e1f-0d2b3c4a5e6f")
+	require.NoError(t, err)
+	require.Equal(t, "Bearer access-token", authorization)
+}
+
+func TestBitbucketFetcher_DataCenter(t *testing.T) {
+	require.Equal(t, "https://api.bitbucket.org", NewBitbucketFetcher(nil, "").BaseURL)
+	require.Equal(t, "https://bitbucket.example.com", NewBitbucketFetcher(nil, "bitbucket.example.com").BaseURL)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintln(w, `{"errors": [{"message": "Authentication failed. Please check your credentials and try again."}]}`)
+			return
+		}
+		if r.URL.Path != "/rest/api/1.0/users/bbuddin" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"errors": [{"context": null, "message": "User nobody does not exist.", "exceptionName": "com.atlassian.bitbucket.user.NoSuchUserException"}]}`)
+			return
+		}
+		f, err := os.Open("testdata/bitbucket_server_user.json")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		io.Copy(w, f)
+	}))
+	defer server.Close()
+
+	f := BitbucketFetcher{
+		Client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		BaseURL: server.URL,
+		Host:    "bitbucket.example.com",
+		Logins:  logins{"bitbucket.example.com": {"", "secret"}},
+	}
+	ca, err := f.Fetch("bbuddin")
+	require.NoError(t, err)
+	require.Equal(t, manifest.Coauthor{
+		ID:    "bbuddin",
+		Name:  "Brett Buddin",
+		Email: "brett@buddin.org",
+		Type:  manifest.CoauthorTypeBitbucket,
+		Host:  "bitbucket.example.com",
+	}, ca)
+
+	_, err = f.Fetch("nobody")
+	var notFound UserNotFoundError
+	require.True(t, errors.As(err, &notFound))
+	require.Equal(t, "User nobody does not exist.", notFound.Message)
+
+	f.Logins = nil
+	_, err = f.Fetch("bbuddin")
+	require.EqualError(t, err, `error fetching "bbuddin" from Bitbucket: Authentication failed. Please check your credentials and try again.`)
+}
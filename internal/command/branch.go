@@ -0,0 +1,197 @@
+package command
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/brettbuddin/partner/internal/repository"
+)
+
+const excludeBranchKey = "partner.excludeBranch"
+
+// TemplateSetBranch links coauthors to the current branch. They take
+// precedence over coauthors activated for the repository whenever the branch
+// is checked out. Branch sets are applied by the prepare-commit-msg hook, so
+// it is installed as well.
+func (c *Command) TemplateSetBranch(ids ...string) error {
+	m, err := c.loadManifest()
+	if err != nil {
+		return err
+	}
+
+	repoPaths, err := c.Paths.Repository()
+	if err != nil {
+		return err
+	}
+	branch, err := currentBranch(repoPaths.Root)
+	if err != nil {
+		return err
+	}
+
+	sets, err := branchSets(repoPaths.Root)
+	if err != nil {
+		return err
+	}
+	ids = uniqueStrings(append(ids, sets[branch]...))
+	coauthors, err := m.Find(ids...)
+	if err != nil {
+		return err
+	}
+
+	ids = ids[:0]
+	for _, ca := range coauthors {
+		ids = append(ids, ca.ID)
+	}
+	if err := repository.SetConfig(repoPaths.Root, repository.ScopeLocal, branchKey(branch), strings.Join(ids, ",")); err != nil {
+		return fmt.Errorf("failed to link coauthors to branch %q: %w", branch, err)
+	}
+	_, err = c.autoInstallHook()
+	return err
+}
+
+// TemplateClearBranch unlinks coauthors from the current branch
+func (c *Command) TemplateClearBranch() error {
+	repoPaths, err := c.Paths.Repository()
+	if err != nil {
+		return err
+	}
+	branch, err := currentBranch(repoPaths.Root)
+	if err != nil {
+		return err
+	}
+
+	sets, err := branchSets(repoPaths.Root)
+	if err != nil {
+		return err
+	}
+	if _, ok := sets[branch]; !ok {
+		return nil
+	}
+	return repository.UnsetConfig(repoPaths.Root, repository.ScopeLocal, branchKey(branch))
+}
+
+// BranchExclude prevents coauthors from being added to commits on branches
+// matching the patterns. Only the prepare-commit-msg hook can keep the
+// trailers in the commit template off those commits, so it is installed as
+// well.
+func (c *Command) BranchExclude(patterns ...string) error {
+	repoPaths, err := c.Paths.Repository()
+	if err != nil {
+		return err
+	}
+	existing, err := repository.ConfigAll(repoPaths.Root, excludeBranchKey)
+	if err != nil {
+		return err
+	}
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid branch pattern %q: %w", pattern, err)
+		}
+		if containsString(existing, pattern) {
+			continue
+		}
+		if err := repository.AddConfig(repoPaths.Root, repository.ScopeLocal, excludeBranchKey, pattern); err != nil {
+			return err
+		}
+	}
+	_, err = c.autoInstallHook()
+	return err
+}
+
+// BranchInclude removes branch patterns added by BranchExclude
+func (c *Command) BranchInclude(patterns ...string) error {
+	repoPaths, err := c.Paths.Repository()
+	if err != nil {
+		return err
+	}
+	existing, err := repository.ConfigAll(repoPaths.Root, excludeBranchKey)
+	if err != nil {
+		return err
+	}
+	for _, pattern := range patterns {
+		if !containsString(existing, pattern) {
+			return fmt.Errorf("branch pattern %q is not excluded", pattern)
+		}
+		if err := repository.UnsetConfigValue(repoPaths.Root, repository.ScopeLocal, excludeBranchKey, pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// branchSets returns the IDs of coauthors linked to each branch
+func branchSets(dir string) (map[string][]string, error) {
+	values, err := repository.ConfigMatching(dir, `^branch\..*\.partnercoauthors$`)
+	if err != nil {
+		return nil, err
+	}
+	sets := map[string][]string{}
+	for key, v := range values {
+		branch := strings.TrimSuffix(strings.TrimPrefix(key, "branch."), ".partnercoauthors")
+		sets[branch] = strings.Split(v, ",")
+	}
+	return sets, nil
+}
+
+// hookNeeded reports whether branch sets or exclusions rely on the
+// prepare-commit-msg hook
+func hookNeeded(dir string) (bool, error) {
+	sets, err := branchSets(dir)
+	if err != nil || len(sets) > 0 {
+		return len(sets) > 0, err
+	}
+	patterns, err := repository.ConfigAll(dir, excludeBranchKey)
+	return len(patterns) > 0, err
+}
+
+// branchExcluded reports whether coauthors should never be added on a branch
+func branchExcluded(dir, branch string) (bool, error) {
+	if branch == "" {
+		return false, nil
+	}
+	patterns, err := repository.ConfigAll(dir, excludeBranchKey)
+	if err != nil {
+		return false, err
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func currentBranch(dir string) (string, error) {
+	branch, err := repository.CurrentBranch(dir)
+	if err != nil {
+		return "", err
+	}
+	if branch == "" {
+		return "", fmt.Errorf("no branch is checked out")
+	}
+	return branch, nil
+}
+
+func branchKey(branch string) string {
+	return "branch." + branch + ".partnerCoauthors"
+}
+
+func sortedBranches(sets map[string][]string) []string {
+	var branches []string
+	for b := range sets {
+		branches = append(branches, b)
+	}
+	sort.Strings(branches)
+	return branches
+}
+
+func containsString(l []string, s string) bool {
+	for _, v := range l {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
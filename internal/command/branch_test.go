@@ -0,0 +1,165 @@
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brettbuddin/partner/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBranchSets(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	runGit(t, cmd.Paths.WorkDir, "checkout", "-q", "-b", "main")
+	runGit(t, cmd.Paths.WorkDir, "-c", "user.name=partner", "-c", "user.email=partner@example.com", "commit", "--allow-empty", "-m", "Initial commit")
+
+	err := cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = cmd.ManifestAdd("persona", "Person A", "a@buddin.org")
+	require.NoError(t, err)
+
+	err = cmd.TemplateSet("brett")
+	require.NoError(t, err)
+
+	// Link a different set to a feature branch
+	runGit(t, cmd.Paths.WorkDir, "checkout", "-q", "-b", "feature/mob")
+	err = cmd.TemplateSetBranch("persona")
+	require.NoError(t, err)
+
+	out := bytes.NewBuffer(nil)
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Equal(t, listExample(`
+ID       NAME      EMAIL         TYPE
+persona  Person A  a@buddin.org  manual
+
+Scope: branch feature/mob
+
+Branch sets:
+  feature/mob  persona
+`), out.String())
+
+	// The hook replaces the trailers contributed by the repository template
+	repoPaths, err := cmd.Paths.Repository()
+	require.NoError(t, err)
+	msg := commitMessageFromTemplate(t, cmd, repoPaths.TemplateFile)
+	require.Equal(t, `
+
+Co-Authored-By: Person A <a@buddin.org>
+
+`, msg)
+
+	// Back on main the repository set applies and the template is left alone
+	runGit(t, cmd.Paths.WorkDir, "checkout", "-q", "main")
+	tmplb, err := ioutil.ReadFile(repoPaths.TemplateFile)
+	require.NoError(t, err)
+	msg = commitMessageFromTemplate(t, cmd, repoPaths.TemplateFile)
+	require.Equal(t, string(tmplb), msg)
+
+	// Excluded branches never get coauthors
+	err = cmd.BranchExclude("ma*")
+	require.NoError(t, err)
+	msg = commitMessageFromTemplate(t, cmd, repoPaths.TemplateFile)
+	require.Equal(t, "\n\n", msg)
+
+	out.Truncate(0)
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Equal(t, listExample(`
+Coauthors are never added on branch main
+
+Branch sets:
+  feature/mob  persona
+`), out.String())
+
+	err = cmd.BranchInclude("ma*")
+	require.NoError(t, err)
+	err = cmd.BranchInclude("ma*")
+	require.Error(t, err)
+
+	// Unlink the branch set
+	runGit(t, cmd.Paths.WorkDir, "checkout", "-q", "feature/mob")
+	err = cmd.TemplateClearBranch()
+	require.NoError(t, err)
+	out.Truncate(0)
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "Scope: repository")
+	require.NotContains(t, out.String(), "Branch sets")
+}
+
+func TestBranchSets_Hook(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	runGit(t, cmd.Paths.WorkDir, "checkout", "-q", "-b", "main")
+	hookPath := filepath.Join(cmd.Paths.WorkDir, ".git/hooks", HookPrepareCommitMsg)
+
+	err := cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = cmd.TemplateSet("brett")
+	require.NoError(t, err)
+	_, err = os.Stat(hookPath)
+	require.True(t, os.IsNotExist(err), "hook was installed")
+
+	// Exclusions rely on the hook to keep the template's trailers off commits
+	err = cmd.BranchExclude("main")
+	require.NoError(t, err)
+	_, err = os.Stat(hookPath)
+	require.NoError(t, err)
+	err = cmd.BranchInclude("main")
+	require.NoError(t, err)
+
+	// Clearing the repository session leaves branch sets working
+	runGit(t, cmd.Paths.WorkDir, "checkout", "-q", "-b", "feature")
+	err = cmd.TemplateSetBranch("brett")
+	require.NoError(t, err)
+	err = cmd.TemplateClear()
+	require.NoError(t, err)
+	_, err = os.Stat(hookPath)
+	require.NoError(t, err)
+
+	err = cmd.TemplateClearBranch()
+	require.NoError(t, err)
+	err = cmd.TemplateClear()
+	require.NoError(t, err)
+	_, err = os.Stat(hookPath)
+	require.True(t, os.IsNotExist(err), "hook was not removed")
+}
+
+func TestCurrentBranch_Detached(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	runGit(t, cmd.Paths.WorkDir, "checkout", "-q", "-b", "main")
+	runGit(t, cmd.Paths.WorkDir, "-c", "user.name=partner", "-c", "user.email=partner@example.com", "commit", "--allow-empty", "-m", "Initial commit")
+
+	branch, err := repository.CurrentBranch(cmd.Paths.WorkDir)
+	require.NoError(t, err)
+	require.Equal(t, "main", branch)
+
+	runGit(t, cmd.Paths.WorkDir, "checkout", "-q", "--detach")
+	branch, err = repository.CurrentBranch(cmd.Paths.WorkDir)
+	require.NoError(t, err)
+	require.Empty(t, branch)
+
+	_, err = repository.CurrentBranch(os.TempDir())
+	require.Error(t, err)
+}
+
+// commitMessageFromTemplate runs the prepare-commit-msg hook against a commit
+// message created from the commit template, as git would
+func commitMessageFromTemplate(t *testing.T, cmd *Command, templateFile string) string {
+	t.Helper()
+
+	tmplb, err := ioutil.ReadFile(templateFile)
+	require.NoError(t, err)
+	msgFile := filepath.Join(cmd.Paths.WorkDir, ".git/COMMIT_EDITMSG")
+	err = ioutil.WriteFile(msgFile, tmplb, 0644)
+	require.NoError(t, err)
+
+	err = cmd.HookPrepareCommitMsg(msgFile)
+	require.NoError(t, err)
+	b, err := ioutil.ReadFile(msgFile)
+	require.NoError(t, err)
+	return string(b)
+}
@@ -5,15 +5,29 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime/debug"
 	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/atrox/homedir"
 	"github.com/brettbuddin/partner/internal/manifest"
 	"github.com/brettbuddin/partner/internal/repository"
+	"github.com/brettbuddin/partner/internal/template"
 )
 
+// now is the clock used for session expiry
+var now = time.Now
+
+// Version returns the version of partner, as recorded by the Go toolchain
+func Version() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
 // Command holds actions for commands
 type Command struct {
 	Paths Paths
@@ -28,25 +42,79 @@ func New(paths Paths) *Command {
 type Paths struct {
 	WorkDir      string
 	ManifestFile string
+	// GlobalTemplateFile is the commit template used for every repository
+	// when coauthors are activated globally.
+	GlobalTemplateFile string
 }
 
 // Repository returns paths relative to the root of the Git project. If no Git
-// repository (.git directory) is found, an error will be returned.
+// repository is found, an error will be returned.
+//
+// The commit template is shared by all worktrees of a repository, unless the
+// partner.perWorktree git configuration is enabled, in which case each
+// worktree has its own.
 func (p Paths) Repository() (RepositoryPaths, error) {
 	root, err := repository.Root(p.WorkDir)
 	if err != nil {
 		return RepositoryPaths{}, err
 	}
+	gitDir, err := repository.GitDir(root)
+	if err != nil {
+		return RepositoryPaths{}, err
+	}
+	commonDir, err := repository.CommonDir(root)
+	if err != nil {
+		return RepositoryPaths{}, err
+	}
+	perWorktree, err := repository.ConfigBool(root, "partner.perWorktree")
+	if err != nil {
+		return RepositoryPaths{}, err
+	}
+
+	scope, dir := repository.ScopeLocal, commonDir
+	if perWorktree {
+		scope, dir = repository.ScopeWorktree, gitDir
+	}
 	return RepositoryPaths{
-		Root:         root,
-		TemplateFile: filepath.Join(root, ".git/gitmessage.txt"),
+		Root:      root,
+		GitDir:    gitDir,
+		CommonDir: commonDir,
+		TemplatePaths: TemplatePaths{
+			Dir:          root,
+			Scope:        scope,
+			TemplateFile: filepath.Join(dir, "gitmessage.txt"),
+			StateFile:    filepath.Join(dir, "partner", "state.json"),
+		},
 	}, nil
 }
 
+// Global returns the paths of the commit template that applies to every
+// repository.
+func (p Paths) Global() TemplatePaths {
+	return TemplatePaths{
+		Dir:          p.WorkDir,
+		Scope:        repository.ScopeGlobal,
+		TemplateFile: p.GlobalTemplateFile,
+		StateFile:    filepath.Join(filepath.Dir(p.GlobalTemplateFile), "state.json"),
+	}
+}
+
 // Repository specific paths
 type RepositoryPaths struct {
-	Root         string
+	Root      string
+	GitDir    string
+	CommonDir string
+	TemplatePaths
+}
+
+// TemplatePaths locates a commit template, the state it is generated from and
+// the git configuration scope it is registered in
+type TemplatePaths struct {
+	// Dir is where git is run to configure the template.
+	Dir          string
+	Scope        repository.Scope
 	TemplateFile string
+	StateFile    string
 }
 
 // DefaultPaths returns calculated Git repository root, commit template and
@@ -60,10 +128,15 @@ func DefaultPaths(workDir string) (Paths, error) {
 	if err != nil {
 		return Paths{}, err
 	}
+	globalTemplatePath, err := homedir.Expand(template.DefaultGlobalPath)
+	if err != nil {
+		return Paths{}, err
+	}
 
 	return Paths{
-		WorkDir:      workDir,
-		ManifestFile: os.ExpandEnv(manifestPath),
+		WorkDir:            workDir,
+		ManifestFile:       os.ExpandEnv(manifestPath),
+		GlobalTemplateFile: globalTemplatePath,
 	}, nil
 }
 
@@ -22,7 +22,7 @@ func TestManifestManagement(t *testing.T) {
 
 	// List the coauthors
 	out := bytes.NewBuffer(nil)
-	err = cmd.ManifestList(out)
+	err = cmd.ManifestList(out, false)
 	require.NoError(t, err)
 	require.Equal(t, listExample(`
 ID     NAME          EMAIL             TYPE
@@ -30,12 +30,12 @@ brett  Brett Buddin  brett@buddin.org  manual
 `), out.String())
 
 	// Remove the coauthor
-	err = cmd.ManifestRemove("brett")
+	err = cmd.ManifestRemove(ioutil.Discard, "brett")
 	require.NoError(t, err)
 
 	// Verify that the coauthor was removed
 	out.Truncate(0)
-	err = cmd.ManifestList(out)
+	err = cmd.ManifestList(out, false)
 	require.NoError(t, err)
 	require.Equal(t, "", out.String())
 }
@@ -59,7 +59,7 @@ func TestActivationWorkflow(t *testing.T) {
 # Managed by partner
 #
 # partner-id: brett
-Co-Authored-By: "Brett Buddin" <brett@buddin.org>
+Co-Authored-By: Brett Buddin <brett@buddin.org>
 `), string(tmplb))
 
 	// Add and activate the second coauthor
@@ -76,6 +76,8 @@ Co-Authored-By: "Brett Buddin" <brett@buddin.org>
 ID       NAME          EMAIL             TYPE
 brett    Brett Buddin  brett@buddin.org  manual
 persona  Person A      a@buddin.org      manual
+
+Scope: repository
 `), out.String())
 
 	// Verify the template contains what we'd expect
@@ -85,9 +87,9 @@ persona  Person A      a@buddin.org      manual
 # Managed by partner
 #
 # partner-id: brett
-Co-Authored-By: "Brett Buddin" <brett@buddin.org>
+Co-Authored-By: Brett Buddin <brett@buddin.org>
 # partner-id: persona
-Co-Authored-By: "Person A" <a@buddin.org>
+Co-Authored-By: Person A <a@buddin.org>
 `), string(tmplb))
 
 	// Unset all active coauthors, and verify that the tool reports nothing
@@ -103,6 +105,112 @@ Co-Authored-By: "Person A" <a@buddin.org>
 	require.Error(t, err)
 }
 
+func TestActivationWorkflow_ExistingCommitTemplate(t *testing.T) {
+	cmd := New(newWorkspace(t))
+
+	skeleton := "feat: \n\n# What changed and why?\n"
+	err := ioutil.WriteFile(filepath.Join(cmd.Paths.WorkDir, "skeleton.txt"), []byte(skeleton), 0644)
+	require.NoError(t, err)
+	runGit(t, cmd.Paths.WorkDir, "config", "commit.template", "skeleton.txt")
+
+	err = cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = cmd.TemplateSet("brett")
+	require.NoError(t, err)
+
+	repoPaths, err := cmd.Paths.Repository()
+	require.NoError(t, err)
+	require.Equal(t, repoPaths.TemplateFile, runGit(t, cmd.Paths.WorkDir, "config", "commit.template"))
+
+	// The original template is kept, with the coauthors following it
+	tmplb, err := ioutil.ReadFile(repoPaths.TemplateFile)
+	require.NoError(t, err)
+	require.Equal(t, `feat: 
+
+# What changed and why?
+
+# Managed by partner
+#
+# partner-id: brett
+Co-Authored-By: Brett Buddin <brett@buddin.org>
+`, string(tmplb))
+
+	// Setting again doesn't lose track of the original
+	err = cmd.TemplateSet("brett")
+	require.NoError(t, err)
+	tmplb2, err := ioutil.ReadFile(repoPaths.TemplateFile)
+	require.NoError(t, err)
+	require.Equal(t, string(tmplb), string(tmplb2))
+
+	// Clearing puts the original configuration back exactly
+	err = cmd.TemplateClear()
+	require.NoError(t, err)
+	require.Equal(t, "skeleton.txt", runGit(t, cmd.Paths.WorkDir, "config", "commit.template"))
+	_, err = runGitErr(cmd.Paths.WorkDir, "config", "partner.previousTemplate")
+	require.Error(t, err)
+}
+
+func TestActivationWorkflow_Global(t *testing.T) {
+	cmd := New(newWorkspace(t))
+
+	err := cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = cmd.ManifestAdd("persona", "Person A", "a@buddin.org")
+	require.NoError(t, err)
+
+	err = cmd.TemplateSetGlobal("brett")
+	require.NoError(t, err)
+	require.Equal(t, cmd.Paths.GlobalTemplateFile, runGit(t, cmd.Paths.WorkDir, "config", "--global", "commit.template"))
+	_, err = runGitErr(cmd.Paths.WorkDir, "config", "--local", "commit.template")
+	require.Error(t, err)
+
+	out := bytes.NewBuffer(nil)
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Equal(t, listExample(`
+ID     NAME          EMAIL             TYPE
+brett  Brett Buddin  brett@buddin.org  manual
+
+Scope: global
+`), out.String())
+
+	// Repository scope wins over global scope
+	err = cmd.TemplateSet("persona")
+	require.NoError(t, err)
+	out.Truncate(0)
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Equal(t, listExample(`
+ID       NAME      EMAIL         TYPE
+persona  Person A  a@buddin.org  manual
+
+Scope: repository
+`), out.String())
+
+	// The global template isn't mistaken for a team template
+	repoPaths, err := cmd.Paths.Repository()
+	require.NoError(t, err)
+	tmplb, err := ioutil.ReadFile(repoPaths.TemplateFile)
+	require.NoError(t, err)
+	require.NotContains(t, string(tmplb), "brett")
+
+	err = cmd.TemplateClear()
+	require.NoError(t, err)
+	out.Truncate(0)
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "Scope: global")
+
+	err = cmd.TemplateClearGlobal()
+	require.NoError(t, err)
+	out.Truncate(0)
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Equal(t, "", out.String())
+	_, err = runGitErr(cmd.Paths.WorkDir, "config", "--global", "commit.template")
+	require.Error(t, err)
+}
+
 func newWorkspace(t *testing.T) Paths {
 	t.Helper()
 
@@ -120,9 +228,18 @@ func newWorkspace(t *testing.T) Paths {
 	err = cmd.Run()
 	require.NoError(t, err)
 
+	// Keep global git configuration written by the tests out of the real
+	// home directory.
+	home := os.Getenv("HOME")
+	os.Setenv("HOME", filepath.Join(absTmp, "home"))
+	t.Cleanup(func() {
+		os.Setenv("HOME", home)
+	})
+
 	return Paths{
-		WorkDir:      absTmp,
-		ManifestFile: filepath.Join(absTmp, "manifest.json"),
+		WorkDir:            absTmp,
+		ManifestFile:       filepath.Join(absTmp, "manifest.json"),
+		GlobalTemplateFile: filepath.Join(absTmp, "home/.config/partner/gitmessage.txt"),
 	}
 }
 
@@ -0,0 +1,132 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/brettbuddin/partner/internal/state"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestAdd_ConcurrentGoroutines(t *testing.T) {
+	cmd := New(newWorkspace(t))
+
+	const n = 20
+	var (
+		wg   sync.WaitGroup
+		errs = make(chan error, n)
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("person%d", i)
+			errs <- New(cmd.Paths).ManifestAdd(id, id, id+"@buddin.org")
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	m, err := manifest.Load(cmd.Paths.ManifestFile)
+	require.NoError(t, err)
+	require.Len(t, m.Coauthors, n)
+}
+
+func TestManifestAdd_ConcurrentProcesses(t *testing.T) {
+	cmd := New(newWorkspace(t))
+
+	const (
+		processes = 4
+		adds      = 10
+	)
+	var (
+		wg   sync.WaitGroup
+		errs = make(chan error, processes)
+	)
+	for p := 0; p < processes; p++ {
+		helper := exec.Command(os.Args[0], "-test.run=TestHelperProcess")
+		helper.Env = append(os.Environ(),
+			"PARTNER_HELPER_PROCESS=1",
+			"PARTNER_HELPER_WORKDIR="+cmd.Paths.WorkDir,
+			"PARTNER_HELPER_MANIFEST="+cmd.Paths.ManifestFile,
+			"PARTNER_HELPER_PREFIX=p"+strconv.Itoa(p),
+			"PARTNER_HELPER_ADDS="+strconv.Itoa(adds),
+		)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if out, err := helper.CombinedOutput(); err != nil {
+				errs <- fmt.Errorf("%v: %s", err, out)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	m, err := manifest.Load(cmd.Paths.ManifestFile)
+	require.NoError(t, err)
+	require.Len(t, m.Coauthors, processes*adds)
+}
+
+// TestHelperProcess isn't a real test. It adds coauthors to a manifest from a
+// separate process for TestManifestAdd_ConcurrentProcesses.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("PARTNER_HELPER_PROCESS") != "1" {
+		return
+	}
+	cmd := New(Paths{
+		WorkDir:      os.Getenv("PARTNER_HELPER_WORKDIR"),
+		ManifestFile: os.Getenv("PARTNER_HELPER_MANIFEST"),
+	})
+	adds, err := strconv.Atoi(os.Getenv("PARTNER_HELPER_ADDS"))
+	require.NoError(t, err)
+	for i := 0; i < adds; i++ {
+		id := fmt.Sprintf("%s-%d", os.Getenv("PARTNER_HELPER_PREFIX"), i)
+		require.NoError(t, cmd.ManifestAdd(id, id, id+"@buddin.org"))
+	}
+}
+
+func TestTemplateSet_Concurrent(t *testing.T) {
+	cmd := New(newWorkspace(t))
+
+	const n = 10
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("person%d", i)
+		require.NoError(t, cmd.ManifestAdd(id, id, id+"@buddin.org"))
+	}
+
+	var (
+		wg   sync.WaitGroup
+		errs = make(chan error, n)
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- New(cmd.Paths).TemplateSet(fmt.Sprintf("person%d", i))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// Every activation was merged into the state, none were lost
+	repoPaths, err := cmd.Paths.Repository()
+	require.NoError(t, err)
+	s, err := state.Load(repoPaths.StateFile)
+	require.NoError(t, err)
+	require.Len(t, s.IDs, n)
+}
@@ -0,0 +1,164 @@
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionExpiry(t *testing.T) {
+	cmd := New(newWorkspace(t))
+
+	clock := time.Date(2021, 1, 4, 9, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+
+	err := cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	out := bytes.NewBuffer(nil)
+	err = cmd.TemplateSetFor(out, 4*time.Hour, "brett")
+	require.NoError(t, err)
+	require.Equal(t, "Installed the prepare-commit-msg hook to stop crediting the coauthors once they expire\n", out.String())
+
+	// The hook is installed to enforce the expiry
+	out.Reset()
+	err = cmd.HookStatus(out)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "prepare-commit-msg  installed")
+
+	clock = clock.Add(time.Hour)
+	out.Truncate(0)
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "Expires: in 3h0m (")
+
+	// A template written before the expiry is stripped by the hook afterwards
+	repoPaths, err := cmd.Paths.Repository()
+	require.NoError(t, err)
+	clock = clock.Add(3 * time.Hour)
+	msg := commitMessageFromTemplate(t, cmd, repoPaths.TemplateFile)
+	require.Equal(t, "\n\n", msg)
+
+	// The session is cleared
+	_, err = os.Stat(repoPaths.TemplateFile)
+	require.True(t, os.IsNotExist(err), "expired template was not removed")
+	out.Truncate(0)
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Equal(t, "", out.String())
+}
+
+func TestSessionExpiry_SetAfterExpiry(t *testing.T) {
+	cmd := New(newWorkspace(t))
+
+	clock := time.Date(2021, 1, 4, 9, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+
+	err := cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = cmd.ManifestAdd("persona", "Person A", "a@buddin.org")
+	require.NoError(t, err)
+	err = cmd.TemplateSetFor(ioutil.Discard, time.Hour, "brett")
+	require.NoError(t, err)
+
+	// Coauthors from an expired session aren't carried into the next one
+	clock = clock.Add(24 * time.Hour)
+	err = cmd.TemplateSetFor(ioutil.Discard, time.Hour, "persona")
+	require.NoError(t, err)
+
+	set, err := cmd.activeSet()
+	require.NoError(t, err)
+	require.Equal(t, []string{"persona"}, set.IDs)
+	repoPaths, err := cmd.Paths.Repository()
+	require.NoError(t, err)
+	msg := commitMessageFromTemplate(t, cmd, repoPaths.TemplateFile)
+	require.NotContains(t, msg, "brett@buddin.org")
+}
+
+func TestSessionExpiry_EditAfterExpiry(t *testing.T) {
+	cmd := New(newWorkspace(t))
+
+	clock := time.Date(2021, 1, 4, 9, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+
+	err := cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = cmd.TemplateSetFor(ioutil.Discard, time.Hour, "brett")
+	require.NoError(t, err)
+
+	// Editing a coauthor of an expired session doesn't revive it
+	clock = clock.Add(2 * time.Hour)
+	err = cmd.ManifestEdit("brett", manifest.Edit{ID: "bb"})
+	require.NoError(t, err)
+
+	repoPaths, err := cmd.Paths.Repository()
+	require.NoError(t, err)
+	_, err = os.Stat(repoPaths.StateFile)
+	require.True(t, os.IsNotExist(err), "expired state was not removed")
+	_, err = os.Stat(repoPaths.TemplateFile)
+	require.True(t, os.IsNotExist(err), "expired template was not removed")
+}
+
+func TestSessionExpiry_HookInstallFails(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	err := cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+
+	// With nowhere to put partner's hook, the session isn't started at all
+	hookPath := filepath.Join(cmd.Paths.WorkDir, ".git/hooks", HookPrepareCommitMsg)
+	for _, path := range []string{hookPath, hookPath + ".partner-legacy"} {
+		err = ioutil.WriteFile(path, []byte("#!/bin/sh\nexit 0\n"), 0755)
+		require.NoError(t, err)
+	}
+	err = cmd.TemplateSetFor(ioutil.Discard, time.Hour, "brett")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to install the prepare-commit-msg hook, which expiring coauthors rely on")
+
+	repoPaths, err := cmd.Paths.Repository()
+	require.NoError(t, err)
+	_, err = os.Stat(repoPaths.StateFile)
+	require.True(t, os.IsNotExist(err), "state was written")
+	_, err = os.Stat(repoPaths.TemplateFile)
+	require.True(t, os.IsNotExist(err), "template was written")
+}
+
+func TestSessionExpiry_DefaultTTL(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	runGit(t, cmd.Paths.WorkDir, "config", "partner.ttl", "30m")
+
+	err := cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = cmd.TemplateSet("brett")
+	require.NoError(t, err)
+
+	out := bytes.NewBuffer(nil)
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "Expires: in 30m (")
+}
+
+func TestSessionExpiry_Global(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	runGit(t, cmd.Paths.WorkDir, "config", "partner.ttl", "30m")
+
+	// Nothing could remove the trailers from the global template once they
+	// expire, so global sessions don't
+	err := cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = cmd.TemplateSetGlobal("brett")
+	require.NoError(t, err)
+
+	out := bytes.NewBuffer(nil)
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "Scope: global\n")
+	require.NotContains(t, out.String(), "Expires")
+}
@@ -0,0 +1,136 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/brettbuddin/partner/internal/repository"
+)
+
+// gerritXSSIPrefix precedes every JSON response from Gerrit's REST API, to
+// keep it from being run as a script
+const gerritXSSIPrefix = ")]}'"
+
+// GerritFetcher fetches coauthors from a Gerrit Code Review instance. Gerrit
+// rejects pushes whose commit email doesn't belong to a registered account,
+// so coauthors are credited with their preferred email address.
+type GerritFetcher struct {
+	Client  *http.Client
+	BaseURL string
+	// Host is the instance coauthors are fetched from. There's no public
+	// Gerrit instance to assume.
+	Host string
+	// Username authenticates requests with the password from Logins, in
+	// place of the username Logins finds.
+	Username string
+	// Logins authenticates requests, if set
+	Logins LoginSource
+}
+
+// NewGerritFetcher returns a fetcher for the Gerrit instance at host
+func NewGerritFetcher(client *http.Client, host string) *GerritFetcher {
+	return &GerritFetcher{Client: client, BaseURL: "https://" + host, Host: host}
+}
+
+// DefaultGerritUsername returns the username to authenticate with Gerrit as,
+// according to the partner.gerritUsername git configuration
+func DefaultGerritUsername(dir string) (string, error) {
+	username, _, err := repository.Config(dir, "partner.gerritUsername")
+	return username, err
+}
+
+func (f *GerritFetcher) Fetch(username string) (manifest.Coauthor, error) {
+	host := strings.ToLower(f.Host)
+	var login, password string
+	if f.Logins != nil {
+		var err error
+		if login, password, err = f.Logins.Login(host); err != nil {
+			return manifest.Coauthor{}, fmt.Errorf("failed to find a password for %s: %w", host, err)
+		}
+	}
+	if f.Username != "" {
+		login = f.Username
+	}
+
+	// Authenticated requests are made to endpoints under /a/
+	endpoint := f.BaseURL + "/accounts/"
+	if password != "" {
+		if login == "" {
+			return manifest.Coauthor{}, fmt.Errorf("a username is required to authenticate with %s; set partner.gerritUsername", host)
+		}
+		endpoint = f.BaseURL + "/a/accounts/"
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return manifest.Coauthor{}, err
+	}
+	parsed.RawQuery = url.Values{
+		"q": {"username:" + username},
+		"o": {"DETAILS"},
+	}.Encode()
+	r, err := http.NewRequest(http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return manifest.Coauthor{}, err
+	}
+	if password != "" {
+		r.SetBasicAuth(login, password)
+	}
+	resp, err := f.Client.Do(r)
+	if err != nil {
+		return manifest.Coauthor{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return manifest.Coauthor{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		// Gerrit's errors are plain text
+		message := strings.TrimSpace(string(body))
+		if message == "" {
+			message = http.StatusText(resp.StatusCode)
+		}
+		return manifest.Coauthor{}, fmt.Errorf("error fetching %q from Gerrit: %s", username, message)
+	}
+
+	var accounts []struct {
+		Username    string `json:"username"`
+		Name        string `json:"name"`
+		DisplayName string `json:"display_name"`
+		Email       string `json:"email"`
+	}
+	body = bytes.TrimPrefix(body, []byte(gerritXSSIPrefix))
+	if err := json.Unmarshal(body, &accounts); err != nil {
+		return manifest.Coauthor{}, err
+	}
+	for _, account := range accounts {
+		if !strings.EqualFold(account.Username, username) {
+			continue
+		}
+		if account.Email == "" {
+			return manifest.Coauthor{}, fmt.Errorf("Gerrit doesn't share the email address of %q; authenticate to see it", username)
+		}
+		name := account.DisplayName
+		if name == "" {
+			name = account.Name
+		}
+		if name == "" {
+			name = account.Username
+		}
+		return manifest.Coauthor{
+			Email: account.Email,
+			ID:    account.Username,
+			Name:  name,
+			Type:  manifest.CoauthorTypeGerrit,
+			Host:  host,
+		}, nil
+	}
+	return manifest.Coauthor{}, UserNotFoundError{Username: username, Service: "Gerrit", Message: "no account has that username"}
+}
@@ -0,0 +1,90 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/stretchr/testify/require"
+)
+
+type logins map[string][2]string
+
+func (l logins) Login(host string) (string, string, error) {
+	return l[host][0], l[host][1], nil
+}
+
+func TestGerritFetcher(t *testing.T) {
+	require.Equal(t, "https://review.example.com", NewGerritFetcher(nil, "review.example.com").BaseURL)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		switch {
+		case r.URL.Path == "/a/accounts/" && (!ok || username != "brett" || password != "secret"):
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintln(w, "Unauthorized")
+			return
+		case r.URL.Path != "/accounts/" && r.URL.Path != "/a/accounts/":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, "Not Found")
+			return
+		case r.URL.Query().Get("o") != "DETAILS":
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		case r.URL.Query().Get("q") != "username:bbuddin":
+			fmt.Fprintln(w, ")]}'")
+			fmt.Fprintln(w, "[]")
+			return
+		}
+		f, err := os.Open("testdata/gerrit_accounts.json")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		io.Copy(w, f)
+	}))
+	defer server.Close()
+
+	f := GerritFetcher{
+		Client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		BaseURL: server.URL,
+		Host:    "review.example.com",
+	}
+	ca, err := f.Fetch("bbuddin")
+	require.NoError(t, err)
+	require.Equal(t, manifest.Coauthor{
+		ID:    "bbuddin",
+		Name:  "Brett",
+		Email: "brett@buddin.org",
+		Type:  manifest.CoauthorTypeGerrit,
+		Host:  "review.example.com",
+	}, ca)
+
+	_, err = f.Fetch("nobody")
+	var notFound UserNotFoundError
+	require.True(t, errors.As(err, &notFound))
+
+	// Authenticated requests use HTTP basic auth under /a/
+	f.Logins = logins{"review.example.com": {"brett", "secret"}}
+	_, err = f.Fetch("bbuddin")
+	require.NoError(t, err)
+
+	f.Username = "someone-else"
+	_, err = f.Fetch("bbuddin")
+	require.EqualError(t, err, `error fetching "bbuddin" from Gerrit: Unauthorized`)
+
+	// A password without a username can't be used
+	f.Username = ""
+	f.Logins = logins{"review.example.com": {"", "secret"}}
+	_, err = f.Fetch("bbuddin")
+	require.EqualError(t, err, "a username is required to authenticate with review.example.com; set partner.gerritUsername")
+}
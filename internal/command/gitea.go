@@ -0,0 +1,139 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/brettbuddin/partner/internal/repository"
+)
+
+// giteaHost is the host of Codeberg, the largest public Forgejo instance
+const giteaHost = "codeberg.org"
+
+// GiteaFetcher fetches coauthors from a Gitea or Forgejo instance, such as
+// Codeberg
+type GiteaFetcher struct {
+	Client  *http.Client
+	BaseURL string
+	// Host is the instance coauthors are fetched from. codeberg.org is
+	// assumed when empty.
+	Host string
+	// NoreplyDomain is the domain of the instance's noreply email addresses,
+	// when it isn't the default of noreply.<host>.
+	NoreplyDomain string
+	// Tokens authenticates requests, if set
+	Tokens TokenSource
+}
+
+// NewGiteaFetcher returns a fetcher for the Gitea instance at host. An empty
+// host is codeberg.org.
+func NewGiteaFetcher(client *http.Client, host string) *GiteaFetcher {
+	if host == "" {
+		host = giteaHost
+	}
+	return &GiteaFetcher{Client: client, BaseURL: "https://" + host, Host: host}
+}
+
+// DefaultGiteaNoreplyDomain returns the noreply domain of the default Gitea
+// instance, according to the partner.giteaNoreplyDomain git configuration
+func DefaultGiteaNoreplyDomain(dir string) (string, error) {
+	domain, _, err := repository.Config(dir, "partner.giteaNoreplyDomain")
+	return domain, err
+}
+
+func (f *GiteaFetcher) host() string {
+	if f.Host == "" {
+		return giteaHost
+	}
+	return strings.ToLower(f.Host)
+}
+
+func (f *GiteaFetcher) noreplyDomain() string {
+	if f.NoreplyDomain == "" {
+		return "noreply." + f.host()
+	}
+	return f.NoreplyDomain
+}
+
+func (f *GiteaFetcher) Fetch(username string) (manifest.Coauthor, error) {
+	parsed, err := url.Parse(fmt.Sprintf("%s/api/v1/users/%s", f.BaseURL, url.PathEscape(username)))
+	if err != nil {
+		return manifest.Coauthor{}, err
+	}
+	r, err := http.NewRequest(http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return manifest.Coauthor{}, err
+	}
+	if err := authorize(r, f.Tokens, f.host()); err != nil {
+		return manifest.Coauthor{}, err
+	}
+	resp, err := f.Client.Do(r)
+	if err != nil {
+		return manifest.Coauthor{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var giteaError struct {
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&giteaError); err != nil {
+			return manifest.Coauthor{}, err
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return manifest.Coauthor{}, UserNotFoundError{Username: username, Service: "Gitea", Message: giteaError.Message}
+		}
+		return manifest.Coauthor{}, fmt.Errorf("error fetching %q from Gitea: %s", username, giteaError.Message)
+	}
+
+	var user struct {
+		Login    string `json:"login"`
+		FullName string `json:"full_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return manifest.Coauthor{}, err
+	}
+	name := user.FullName
+	if name == "" {
+		name = user.Login
+	}
+	return manifest.Coauthor{
+		Email: fmt.Sprintf("%s@%s", strings.ToLower(user.Login), f.noreplyDomain()),
+		ID:    user.Login,
+		Name:  name,
+		Type:  manifest.CoauthorTypeGitea,
+		Host:  f.host(),
+	}, nil
+}
+
+// Refresh looks up a coauthor fetched from Gitea again, on the instance they
+// were fetched from. Gitea has no stable account ID in its noreply addresses,
+// so renamed accounts aren't found. The username is taken from the coauthor's
+// noreply address, which outlives changes to their ID, and the noreply domain
+// they were fetched with is kept. Only coauthors credited with another address
+// are looked up by ID.
+func (f *GiteaFetcher) Refresh(ca manifest.Coauthor) (manifest.Coauthor, error) {
+	if ca.Host != "" && !strings.EqualFold(ca.Host, f.host()) {
+		other := NewGiteaFetcher(f.Client, ca.Host)
+		other.Tokens = f.Tokens
+		return other.Refresh(ca)
+	}
+	i := strings.LastIndex(ca.Email, "@")
+	if i < 0 {
+		return f.Fetch(coauthorLogin(ca))
+	}
+	login, domain := ca.Email[:i], ca.Email[i+1:]
+	noreply := strings.EqualFold(domain, f.noreplyDomain()) || strings.EqualFold(domain, "noreply."+f.host())
+	// Coauthors fetched with a noreply domain that's no longer configured
+	// can still be recognized by their ID
+	if !noreply && !strings.EqualFold(login, coauthorLogin(ca)) {
+		return f.Fetch(coauthorLogin(ca))
+	}
+	other := *f
+	other.NoreplyDomain = domain
+	return other.Fetch(login)
+}
@@ -0,0 +1,111 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGiteaFetcher(t *testing.T) {
+	require.Equal(t, "https://codeberg.org", NewGiteaFetcher(nil, "").BaseURL)
+	require.Equal(t, "https://gitea.example.com", NewGiteaFetcher(nil, "gitea.example.com").BaseURL)
+
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		if r.URL.Path != "/api/v1/users/brettbuddin" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"message": "user redirect does not exist [name: brettbuddin-doesntexist]", "url": "https://codeberg.org/api/swagger"}`)
+			return
+		}
+		f, err := os.Open("testdata/gitea_user.json")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		io.Copy(w, f)
+	}))
+	defer server.Close()
+
+	f := GiteaFetcher{
+		Client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		BaseURL: server.URL,
+		Tokens:  tokens{"codeberg.org": "secret"},
+	}
+	ca, err := f.Fetch("brettbuddin")
+	require.NoError(t, err)
+	require.Equal(t, manifest.Coauthor{
+		ID:    "brettbuddin",
+		Name:  "Brett Buddin",
+		Email: "brettbuddin@noreply.codeberg.org",
+		Type:  manifest.CoauthorTypeGitea,
+		Host:  "codeberg.org",
+	}, ca)
+	require.Equal(t, "Bearer secret", authorization)
+
+	_, err = f.Fetch("brettbuddin-doesntexist")
+	var notFound UserNotFoundError
+	require.True(t, errors.As(err, &notFound))
+	require.Equal(t, "user redirect does not exist [name: brettbuddin-doesntexist]", notFound.Message)
+
+	// Instances can be configured with their own noreply domain
+	f.Host = "gitea.example.com"
+	f.NoreplyDomain = "users.example.com"
+	ca, err = f.Fetch("brettbuddin")
+	require.NoError(t, err)
+	require.Equal(t, "brettbuddin@users.example.com", ca.Email)
+	require.Equal(t, "gitea.example.com", ca.Host)
+	require.Empty(t, authorization)
+
+	// Refreshing keeps the domain the coauthor was fetched with
+	f.NoreplyDomain = ""
+	ca, err = f.Refresh(manifest.Coauthor{
+		ID:    "brettbuddin",
+		Name:  "Brett",
+		Email: "brettbuddin@users.example.com",
+		Type:  manifest.CoauthorTypeGitea,
+		Host:  "gitea.example.com",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "brettbuddin@users.example.com", ca.Email)
+	require.Equal(t, "Brett Buddin", ca.Name)
+
+	// The username comes from the noreply address, whatever the coauthor's
+	// ID has become
+	f.Host = ""
+	for _, id := range []string{"codeberg.org/brettbuddin", "brett"} {
+		ca, err = f.Refresh(manifest.Coauthor{
+			ID:    id,
+			Name:  "Brett",
+			Email: "brettbuddin@noreply.codeberg.org",
+			Type:  manifest.CoauthorTypeGitea,
+			Host:  "codeberg.org",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "brettbuddin", ca.ID)
+		require.Equal(t, "Brett Buddin", ca.Name)
+	}
+
+	// Other addresses fall back to the ID, without the host it's qualified
+	// with
+	ca, err = f.Refresh(manifest.Coauthor{
+		ID:    "codeberg.org/brettbuddin",
+		Name:  "Brett",
+		Email: "brett@buddin.org",
+		Type:  manifest.CoauthorTypeGitea,
+		Host:  "codeberg.org",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "brettbuddin", ca.ID)
+}
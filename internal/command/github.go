@@ -5,17 +5,66 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strings"
 
 	"github.com/brettbuddin/partner/internal/manifest"
 )
 
+// githubHost is the host of the public GitHub instance
+const githubHost = "github.com"
+
+// githubNoreply matches the noreply email addresses GitHub coauthors are
+// given, capturing their account ID and the host of the GitHub instance
+var githubNoreply = regexp.MustCompile(`^(\d+)\+[^@]+@users\.noreply\.(.+)$`)
+
 type GitHubFetcher struct {
 	Client  *http.Client
 	BaseURL string
+	// Host is the GitHub instance coauthors are fetched from, such as a
+	// GitHub Enterprise Server. github.com is assumed when empty.
+	Host string
+	// Tokens authenticates requests, if set
+	Tokens TokenSource
+}
+
+// NewGitHubFetcher returns a fetcher for the GitHub instance at host. An empty
+// host is github.com.
+func NewGitHubFetcher(client *http.Client, host string) *GitHubFetcher {
+	if host == "" || strings.EqualFold(host, githubHost) {
+		return &GitHubFetcher{Client: client, BaseURL: "https://api.github.com", Host: githubHost}
+	}
+	return &GitHubFetcher{Client: client, BaseURL: "https://" + host + "/api/v3", Host: host}
+}
+
+func (f *GitHubFetcher) host() string {
+	if f.Host == "" {
+		return githubHost
+	}
+	return strings.ToLower(f.Host)
 }
 
 func (f *GitHubFetcher) Fetch(username string) (manifest.Coauthor, error) {
-	parsed, err := url.Parse(fmt.Sprintf("%s/users/%s", f.BaseURL, username))
+	return f.fetch(fmt.Sprintf("%s/users/%s", f.BaseURL, username), username)
+}
+
+// Refresh looks up a coauthor fetched from GitHub again, on the instance they
+// were fetched from. Accounts are looked up by the ID in their noreply email
+// address when possible, so that renamed accounts are still found.
+func (f *GitHubFetcher) Refresh(ca manifest.Coauthor) (manifest.Coauthor, error) {
+	if ca.Host != "" && !strings.EqualFold(ca.Host, f.host()) {
+		other := NewGitHubFetcher(f.Client, ca.Host)
+		other.Tokens = f.Tokens
+		return other.Refresh(ca)
+	}
+	if m := githubNoreply.FindStringSubmatch(ca.Email); m != nil && strings.EqualFold(m[2], f.host()) {
+		return f.fetch(fmt.Sprintf("%s/user/%s", f.BaseURL, m[1]), coauthorLogin(ca))
+	}
+	return f.Fetch(coauthorLogin(ca))
+}
+
+func (f *GitHubFetcher) fetch(rawURL, username string) (manifest.Coauthor, error) {
+	parsed, err := url.Parse(rawURL)
 	if err != nil {
 		return manifest.Coauthor{}, err
 	}
@@ -23,10 +72,14 @@ func (f *GitHubFetcher) Fetch(username string) (manifest.Coauthor, error) {
 	if err != nil {
 		return manifest.Coauthor{}, err
 	}
+	if err := authorize(r, f.Tokens, f.host()); err != nil {
+		return manifest.Coauthor{}, err
+	}
 	resp, err := f.Client.Do(r)
 	if err != nil {
 		return manifest.Coauthor{}, err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		var ghError struct {
@@ -35,6 +88,9 @@ func (f *GitHubFetcher) Fetch(username string) (manifest.Coauthor, error) {
 		if err := json.NewDecoder(resp.Body).Decode(&ghError); err != nil {
 			return manifest.Coauthor{}, err
 		}
+		if resp.StatusCode == http.StatusNotFound {
+			return manifest.Coauthor{}, UserNotFoundError{Username: username, Service: "GitHub", Message: ghError.Message}
+		}
 		return manifest.Coauthor{}, fmt.Errorf("error fetching %q from GitHub: %s", username, ghError.Message)
 	}
 
@@ -47,9 +103,10 @@ func (f *GitHubFetcher) Fetch(username string) (manifest.Coauthor, error) {
 		return manifest.Coauthor{}, err
 	}
 	return manifest.Coauthor{
-		Email: fmt.Sprintf("%d+%s@users.noreply.github.com", user.ID, user.Login),
+		Email: fmt.Sprintf("%d+%s@users.noreply.%s", user.ID, user.Login, f.host()),
 		ID:    user.Login,
 		Name:  user.Name,
 		Type:  manifest.CoauthorTypeGitHub,
+		Host:  f.host(),
 	}, nil
 }
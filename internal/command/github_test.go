@@ -1,6 +1,7 @@
 package command
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -42,6 +43,7 @@ func TestGitHubFetcher(t *testing.T) {
 		Name:  "Brett Buddin",
 		Email: "6059+brettbuddin@users.noreply.github.com",
 		Type:  manifest.CoauthorTypeGitHub,
+		Host:  "github.com",
 	}, ca)
 }
 
@@ -62,3 +64,144 @@ func TestGitHubFetcher_Error(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "Not Found")
 }
+
+func TestGitHubFetcher_Refresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Accounts are looked up by ID, so renamed accounts are found
+		if r.URL.Path != "/user/6059" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"message": "Not Found"}`)
+			return
+		}
+		f, err := os.Open("testdata/github_user.json")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		io.Copy(w, f)
+	}))
+	defer server.Close()
+
+	f := GitHubFetcher{
+		Client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		BaseURL: server.URL,
+	}
+	ca, err := f.Refresh(manifest.Coauthor{
+		ID:    "brett",
+		Name:  "Brett",
+		Email: "6059+brett@users.noreply.github.com",
+		Type:  manifest.CoauthorTypeGitHub,
+	})
+	require.NoError(t, err)
+	require.Equal(t, manifest.Coauthor{
+		ID:    "brettbuddin",
+		Name:  "Brett Buddin",
+		Email: "6059+brettbuddin@users.noreply.github.com",
+		Type:  manifest.CoauthorTypeGitHub,
+		Host:  "github.com",
+	}, ca)
+
+	_, err = f.Refresh(manifest.Coauthor{ID: "someone", Email: "someone@example.com"})
+	var notFound UserNotFoundError
+	require.True(t, errors.As(err, &notFound))
+	require.Equal(t, "someone", notFound.Username)
+}
+
+func TestGitHubFetcher_EnterpriseServer(t *testing.T) {
+	require.Equal(t, "https://api.github.com", NewGitHubFetcher(nil, "").BaseURL)
+	require.Equal(t, "https://github.example.com/api/v3", NewGitHubFetcher(nil, "github.example.com").BaseURL)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/brettbuddin" && r.URL.Path != "/user/6059" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"message": "Not Found"}`)
+			return
+		}
+		f, err := os.Open("testdata/github_user.json")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		io.Copy(w, f)
+	}))
+	defer server.Close()
+
+	f := GitHubFetcher{
+		Client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		BaseURL: server.URL,
+		Host:    "github.example.com",
+	}
+	ca, err := f.Fetch("brettbuddin")
+	require.NoError(t, err)
+	require.Equal(t, manifest.Coauthor{
+		ID:    "brettbuddin",
+		Name:  "Brett Buddin",
+		Email: "6059+brettbuddin@users.noreply.github.example.com",
+		Type:  manifest.CoauthorTypeGitHub,
+		Host:  "github.example.com",
+	}, ca)
+
+	// The account ID in a noreply address only applies to its own instance
+	ca, err = f.Refresh(manifest.Coauthor{
+		ID:    "brett",
+		Email: "6059+brett@users.noreply.github.example.com",
+		Type:  manifest.CoauthorTypeGitHub,
+		Host:  "github.example.com",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "brettbuddin", ca.ID)
+	_, err = f.Refresh(manifest.Coauthor{
+		ID:    "brett",
+		Email: "6059+brett@users.noreply.github.com",
+		Type:  manifest.CoauthorTypeGitHub,
+		Host:  "github.example.com",
+	})
+	var notFound UserNotFoundError
+	require.True(t, errors.As(err, &notFound))
+}
+
+// tokens are API tokens by host
+type tokens map[string]string
+
+func (t tokens) Token(host string) (string, error) {
+	return t[host], nil
+}
+
+func TestGitHubFetcher_Token(t *testing.T) {
+	var authorization []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		f, err := os.Open("testdata/github_user.json")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		io.Copy(w, f)
+	}))
+	defer server.Close()
+
+	f := GitHubFetcher{
+		Client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		BaseURL: server.URL,
+		Tokens:  tokens{"github.com": "secret"},
+	}
+	_, err := f.Fetch("brettbuddin")
+	require.NoError(t, err)
+	_, err = f.Refresh(manifest.Coauthor{ID: "brett", Email: "6059+brett@users.noreply.github.com"})
+	require.NoError(t, err)
+
+	// Without a token for the host, requests are anonymous
+	f.Host = "github.example.com"
+	_, err = f.Fetch("brettbuddin")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Bearer secret", "Bearer secret", ""}, authorization)
+}
@@ -5,13 +5,49 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strings"
 
 	"github.com/brettbuddin/partner/internal/manifest"
 )
 
+// gitlabHost is the host of the public GitLab instance
+const gitlabHost = "gitlab.com"
+
+// gitlabNoreply matches the noreply email addresses GitLab coauthors are
+// given, capturing their account ID and the host of the GitLab instance
+var gitlabNoreply = regexp.MustCompile(`^(\d+)-[^@]+@users\.noreply\.(.+)$`)
+
+type gitlabUser struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
 type GitLabFetcher struct {
 	Client  *http.Client
 	BaseURL string
+	// Host is the GitLab instance coauthors are fetched from, such as a
+	// self-managed one. gitlab.com is assumed when empty.
+	Host string
+	// Tokens authenticates requests, if set
+	Tokens TokenSource
+}
+
+// NewGitLabFetcher returns a fetcher for the GitLab instance at host. An empty
+// host is gitlab.com.
+func NewGitLabFetcher(client *http.Client, host string) *GitLabFetcher {
+	if host == "" {
+		host = gitlabHost
+	}
+	return &GitLabFetcher{Client: client, BaseURL: "https://" + host, Host: host}
+}
+
+func (f *GitLabFetcher) host() string {
+	if f.Host == "" {
+		return gitlabHost
+	}
+	return strings.ToLower(f.Host)
 }
 
 func (f *GitLabFetcher) Fetch(username string) (manifest.Coauthor, error) {
@@ -23,42 +59,75 @@ func (f *GitLabFetcher) Fetch(username string) (manifest.Coauthor, error) {
 	query.Set("username", username)
 	parsed.RawQuery = query.Encode()
 
+	var users []*gitlabUser
+	if err := f.get(parsed.String(), username, &users); err != nil {
+		return manifest.Coauthor{}, err
+	}
+	if len(users) == 0 {
+		return manifest.Coauthor{}, UserNotFoundError{Username: username, Service: "GitLab", Message: "username not found"}
+	}
+	return f.coauthor(users[0]), nil
+}
+
+// Refresh looks up a coauthor fetched from GitLab again, on the instance they
+// were fetched from. Accounts are looked up by the ID in their noreply email
+// address when possible, so that renamed accounts are still found.
+func (f *GitLabFetcher) Refresh(ca manifest.Coauthor) (manifest.Coauthor, error) {
+	if ca.Host != "" && !strings.EqualFold(ca.Host, f.host()) {
+		other := NewGitLabFetcher(f.Client, ca.Host)
+		other.Tokens = f.Tokens
+		return other.Refresh(ca)
+	}
+	m := gitlabNoreply.FindStringSubmatch(ca.Email)
+	if m == nil || !strings.EqualFold(m[2], f.host()) {
+		return f.Fetch(coauthorLogin(ca))
+	}
+	var user gitlabUser
+	if err := f.get(fmt.Sprintf("%s/api/v4/users/%s", f.BaseURL, m[1]), coauthorLogin(ca), &user); err != nil {
+		return manifest.Coauthor{}, err
+	}
+	return f.coauthor(&user), nil
+}
+
+func (f *GitLabFetcher) get(rawURL, username string, v interface{}) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
 	r, err := http.NewRequest(http.MethodGet, parsed.String(), nil)
 	if err != nil {
-		return manifest.Coauthor{}, err
+		return err
+	}
+	if err := authorize(r, f.Tokens, f.host()); err != nil {
+		return err
 	}
 	resp, err := f.Client.Do(r)
 	if err != nil {
-		return manifest.Coauthor{}, err
+		return err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		var ghError struct {
 			Message string `json:"message"`
 		}
 		if err := json.NewDecoder(resp.Body).Decode(&ghError); err != nil {
-			return manifest.Coauthor{}, err
+			return err
 		}
-		return manifest.Coauthor{}, fmt.Errorf("error fetching %q from GitLab: %s", username, ghError.Message)
-	}
-
-	var users []*struct {
-		ID       int    `json:"id"`
-		Username string `json:"username"`
-		Name     string `json:"name"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
-		return manifest.Coauthor{}, err
-	}
-	if len(users) == 0 {
-		return manifest.Coauthor{}, fmt.Errorf("error fetching %q from GitLab: username not found", username)
+		if resp.StatusCode == http.StatusNotFound {
+			return UserNotFoundError{Username: username, Service: "GitLab", Message: ghError.Message}
+		}
+		return fmt.Errorf("error fetching %q from GitLab: %s", username, ghError.Message)
 	}
-	user := users[0]
+	return json.NewDecoder(resp.Body).Decode(v)
+}
 
+func (f *GitLabFetcher) coauthor(user *gitlabUser) manifest.Coauthor {
 	return manifest.Coauthor{
-		Email: fmt.Sprintf("%d-%s@users.noreply.gitlab.com", user.ID, user.Username),
+		Email: fmt.Sprintf("%d-%s@users.noreply.%s", user.ID, user.Username, f.host()),
 		ID:    user.Username,
 		Name:  user.Name,
 		Type:  manifest.CoauthorTypeGitLab,
-	}, nil
+		Host:  f.host(),
+	}
 }
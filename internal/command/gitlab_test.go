@@ -1,6 +1,7 @@
 package command
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -46,6 +47,7 @@ func TestGitLabFetcher(t *testing.T) {
 		Name:  "Brett Buddin",
 		Email: "4453516-brettbuddin@users.noreply.gitlab.com",
 		Type:  manifest.CoauthorTypeGitLab,
+		Host:  "gitlab.com",
 	}, ca)
 }
 
@@ -65,3 +67,106 @@ func TestGitLabFetcher_Error(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "username not found")
 }
+
+func TestGitLabFetcher_Refresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/users/4453516" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"message": "404 User Not Found"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"id": 4453516, "name": "Brett Buddin", "username": "brettbuddin"}`)
+	}))
+	defer server.Close()
+
+	f := GitLabFetcher{
+		Client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		BaseURL: server.URL,
+	}
+	ca, err := f.Refresh(manifest.Coauthor{
+		ID:    "brett",
+		Name:  "Brett",
+		Email: "4453516-brett@users.noreply.gitlab.com",
+		Type:  manifest.CoauthorTypeGitLab,
+	})
+	require.NoError(t, err)
+	require.Equal(t, manifest.Coauthor{
+		ID:    "brettbuddin",
+		Name:  "Brett Buddin",
+		Email: "4453516-brettbuddin@users.noreply.gitlab.com",
+		Type:  manifest.CoauthorTypeGitLab,
+		Host:  "gitlab.com",
+	}, ca)
+
+	_, err = f.Refresh(manifest.Coauthor{ID: "gone", Email: "1-gone@users.noreply.gitlab.com"})
+	var notFound UserNotFoundError
+	require.True(t, errors.As(err, &notFound))
+	require.Equal(t, "404 User Not Found", notFound.Message)
+}
+
+func TestGitLabFetcher_SelfManaged(t *testing.T) {
+	require.Equal(t, "https://gitlab.com", NewGitLabFetcher(nil, "").BaseURL)
+	require.Equal(t, "https://gitlab.example.com", NewGitLabFetcher(nil, "gitlab.example.com").BaseURL)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/users/17" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"message": "404 User Not Found"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"id": 17, "name": "Brett Buddin", "username": "brettbuddin"}`)
+	}))
+	defer server.Close()
+
+	f := GitLabFetcher{
+		Client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		BaseURL: server.URL,
+		Host:    "gitlab.example.com",
+	}
+	ca, err := f.Refresh(manifest.Coauthor{
+		ID:    "brett",
+		Email: "17-brett@users.noreply.gitlab.example.com",
+		Type:  manifest.CoauthorTypeGitLab,
+		Host:  "gitlab.example.com",
+	})
+	require.NoError(t, err)
+	require.Equal(t, manifest.Coauthor{
+		ID:    "brettbuddin",
+		Name:  "Brett Buddin",
+		Email: "17-brettbuddin@users.noreply.gitlab.example.com",
+		Type:  manifest.CoauthorTypeGitLab,
+		Host:  "gitlab.example.com",
+	}, ca)
+}
+
+func TestGitLabFetcher_Token(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintln(w, `{"message": "401 Unauthorized"}`)
+			return
+		}
+		fmt.Fprintln(w, `[{"id": 17, "name": "Brett Buddin", "username": "brettbuddin"}]`)
+	}))
+	defer server.Close()
+
+	f := GitLabFetcher{
+		Client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		BaseURL: server.URL,
+		Host:    "gitlab.example.com",
+		Tokens:  tokens{"gitlab.example.com": "secret"},
+	}
+	ca, err := f.Fetch("brettbuddin")
+	require.NoError(t, err)
+	require.Equal(t, "17-brettbuddin@users.noreply.gitlab.example.com", ca.Email)
+
+	f.Tokens = nil
+	_, err = f.Fetch("brettbuddin")
+	require.EqualError(t, err, `error fetching "brettbuddin" from GitLab: 401 Unauthorized`)
+}
@@ -0,0 +1,213 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/brettbuddin/partner/internal/repository"
+	"github.com/brettbuddin/partner/internal/template"
+	"github.com/brettbuddin/partner/internal/trailer"
+)
+
+// Hooks installed by partner
+const (
+	HookPrepareCommitMsg = "prepare-commit-msg"
+)
+
+var hooks = []string{HookPrepareCommitMsg}
+
+// hookScript chains to any hook that was in place before partner's, and then
+// hands off to partner itself.
+const hookScript = `#!/bin/sh
+` + repository.HookMarker + `
+if [ -x "$0%[1]s" ]; then
+	"$0%[1]s" "$@" || exit $?
+fi
+command -v partner >/dev/null 2>&1 || exit 0
+exec partner hook run %[2]s "$@"
+`
+
+// hookAutoInstalledKey records that partner installed its hooks on its own,
+// rather than being asked to with HookInstall
+const hookAutoInstalledKey = "partner.hookAutoInstalled"
+
+// HookInstall installs git hooks that add the active coauthors to every
+// commit, regardless of how the commit message was authored. Hooks installed
+// this way stay in place until HookUninstall.
+func (c *Command) HookInstall() error {
+	repoPaths, err := c.Paths.Repository()
+	if err != nil {
+		return err
+	}
+	if err := installHooks(repoPaths.Root); err != nil {
+		return err
+	}
+	return unsetHookAutoInstalled(repoPaths.Root)
+}
+
+// autoInstallHook installs partner's git hooks for a feature that relies on
+// them, reporting whether they weren't installed already. Hooks installed this
+// way are removed by TemplateClear once nothing relies on them.
+func (c *Command) autoInstallHook() (bool, error) {
+	repoPaths, err := c.Paths.Repository()
+	if err != nil {
+		return false, err
+	}
+	status, err := repository.Hook(repoPaths.Root, HookPrepareCommitMsg)
+	if err != nil || status.Installed {
+		return false, err
+	}
+	if err := installHooks(repoPaths.Root); err != nil {
+		return false, err
+	}
+	return true, repository.SetConfig(repoPaths.Root, repository.ScopeLocal, hookAutoInstalledKey, "true")
+}
+
+func installHooks(dir string) error {
+	for _, name := range hooks {
+		script := fmt.Sprintf(hookScript, repository.LegacyHookSuffix, name)
+		if err := repository.InstallHook(dir, name, script); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HookUninstall removes partner's git hooks, restoring any hooks they were
+// chained to.
+func (c *Command) HookUninstall() error {
+	repoPaths, err := c.Paths.Repository()
+	if err != nil {
+		return err
+	}
+	for _, name := range hooks {
+		if err := repository.UninstallHook(repoPaths.Root, name); err != nil {
+			return err
+		}
+	}
+	return unsetHookAutoInstalled(repoPaths.Root)
+}
+
+// hookAutoInstalled reports whether partner's hooks were installed by
+// autoInstallHook
+func hookAutoInstalled(dir string) (bool, error) {
+	v, ok, err := repository.Config(dir, hookAutoInstalledKey)
+	return ok && v == "true", err
+}
+
+func unsetHookAutoInstalled(dir string) error {
+	_, ok, err := repository.Config(dir, hookAutoInstalledKey)
+	if err != nil || !ok {
+		return err
+	}
+	return repository.UnsetConfig(dir, repository.ScopeLocal, hookAutoInstalledKey)
+}
+
+// HookStatus lists partner's git hooks and where they are installed
+func (c *Command) HookStatus(w io.Writer) error {
+	repoPaths, err := c.Paths.Repository()
+	if err != nil {
+		return err
+	}
+
+	tabw := tabwriter.NewWriter(w, 5, 2, 2, ' ', 0)
+	fmt.Fprintln(tabw, "HOOK\tSTATUS\tPATH")
+	for _, name := range hooks {
+		status, err := repository.Hook(repoPaths.Root, name)
+		if err != nil {
+			return err
+		}
+		var state string
+		switch {
+		case status.Installed && status.Legacy != "":
+			state = "installed (chained to " + filepath.Base(status.Legacy) + ")"
+		case status.Installed:
+			state = "installed"
+		case status.Foreign:
+			state = "not managed by partner"
+		default:
+			state = "not installed"
+		}
+		fmt.Fprintf(tabw, "%s\t%s\t%s\n", status.Name, state, status.Path)
+	}
+	return tabw.Flush()
+}
+
+// HookPrepareCommitMsg appends the active coauthors' trailers to the commit
+// message file git hands to the prepare-commit-msg hook. Trailers contributed
+// by partner's commit template are replaced when a different set of coauthors
+// is in effect, such as when a branch has its own set.
+func (c *Command) HookPrepareCommitMsg(messageFile string) error {
+	if !filepath.IsAbs(messageFile) {
+		messageFile = filepath.Join(c.Paths.WorkDir, messageFile)
+	}
+
+	set, err := c.activeSet()
+	if err != nil {
+		return err
+	}
+
+	m, err := c.loadManifest()
+	if err != nil {
+		return err
+	}
+	coauthors, err := m.Find(set.IDs...)
+	if err != nil {
+		return err
+	}
+	if coauthors, err = selectIdentities(c.Paths.WorkDir, coauthors); err != nil {
+		return err
+	}
+
+	msg, err := ioutil.ReadFile(messageFile)
+	if err != nil {
+		return fmt.Errorf("failed to read commit message: %w", err)
+	}
+	managed := template.ManagedIDs(msg)
+	if len(managed) == 0 && len(coauthors) == 0 {
+		return nil
+	}
+	// Leave a message built from an up to date template untouched, so that
+	// git can still tell whether the template was edited. A template shared
+	// by several repositories may credit the wrong identities in this one.
+	if !sameIDs(managed, set.IDs) || !creditsAll(msg, coauthors) {
+		msg = template.StripManaged(msg)
+	}
+	msg, err = trailer.AppendCoauthors(c.Paths.WorkDir, msg, coauthors)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(messageFile, msg, 0644)
+}
+
+// creditsAll reports whether a message has the exact trailers crediting each
+// of the coauthors
+func creditsAll(msg []byte, coauthors []manifest.Coauthor) bool {
+	for _, ca := range coauthors {
+		if !strings.Contains(string(msg), trailer.Coauthor(ca).String()+"\n") {
+			return false
+		}
+	}
+	return true
+}
+
+func sameIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := map[string]bool{}
+	for _, id := range a {
+		seen[strings.ToLower(id)] = true
+	}
+	for _, id := range b {
+		if !seen[strings.ToLower(id)] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHookInstall(t *testing.T) {
+	cmd := New(newWorkspace(t))
+
+	err := cmd.HookInstall()
+	require.NoError(t, err)
+
+	hookPath := filepath.Join(cmd.Paths.WorkDir, ".git/hooks", HookPrepareCommitMsg)
+	info, err := os.Stat(hookPath)
+	require.NoError(t, err)
+	require.NotZero(t, info.Mode()&0100, "hook is not executable")
+
+	// Reinstalling over our own hook is fine
+	err = cmd.HookInstall()
+	require.NoError(t, err)
+
+	err = cmd.HookUninstall()
+	require.NoError(t, err)
+	_, err = os.Stat(hookPath)
+	require.True(t, os.IsNotExist(err), "hook was not removed")
+}
+
+func TestHookInstall_ChainsExistingHook(t *testing.T) {
+	cmd := New(newWorkspace(t))
+
+	hookPath := filepath.Join(cmd.Paths.WorkDir, ".git/hooks", HookPrepareCommitMsg)
+	existing := []byte("#!/bin/sh\nexit 0\n")
+	err := ioutil.WriteFile(hookPath, existing, 0755)
+	require.NoError(t, err)
+
+	err = cmd.HookInstall()
+	require.NoError(t, err)
+
+	b, err := ioutil.ReadFile(hookPath + ".partner-legacy")
+	require.NoError(t, err)
+	require.Equal(t, existing, b)
+
+	out := bytes.NewBuffer(nil)
+	err = cmd.HookStatus(out)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "installed (chained to prepare-commit-msg.partner-legacy)")
+
+	// Uninstalling puts the original hook back
+	err = cmd.HookUninstall()
+	require.NoError(t, err)
+	b, err = ioutil.ReadFile(hookPath)
+	require.NoError(t, err)
+	require.Equal(t, existing, b)
+	_, err = os.Stat(hookPath + ".partner-legacy")
+	require.True(t, os.IsNotExist(err), "legacy hook was left behind")
+}
+
+func TestHookInstall_Clear(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	hookPath := filepath.Join(cmd.Paths.WorkDir, ".git/hooks", HookPrepareCommitMsg)
+	err := cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+
+	// Hooks partner installed on its own are removed when clearing
+	err = cmd.TemplateSetFor(ioutil.Discard, time.Hour, "brett")
+	require.NoError(t, err)
+	_, err = os.Stat(hookPath)
+	require.NoError(t, err)
+	err = cmd.TemplateClear()
+	require.NoError(t, err)
+	_, err = os.Stat(hookPath)
+	require.True(t, os.IsNotExist(err), "hook was not removed")
+
+	// while hooks installed on purpose are kept
+	err = cmd.HookInstall()
+	require.NoError(t, err)
+	err = cmd.TemplateSetFor(ioutil.Discard, time.Hour, "brett")
+	require.NoError(t, err)
+	err = cmd.TemplateClear()
+	require.NoError(t, err)
+	_, err = os.Stat(hookPath)
+	require.NoError(t, err)
+
+	// including when they were installed on purpose after partner did
+	err = cmd.HookUninstall()
+	require.NoError(t, err)
+	err = cmd.TemplateSetFor(ioutil.Discard, time.Hour, "brett")
+	require.NoError(t, err)
+	err = cmd.HookInstall()
+	require.NoError(t, err)
+	err = cmd.TemplateClear()
+	require.NoError(t, err)
+	_, err = os.Stat(hookPath)
+	require.NoError(t, err)
+}
+
+func TestHookInstall_HooksPath(t *testing.T) {
+	cmd := New(newWorkspace(t))
+
+	git := exec.Command("git", "config", "core.hooksPath", ".githooks")
+	git.Dir = cmd.Paths.WorkDir
+	require.NoError(t, git.Run())
+
+	err := cmd.HookInstall()
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(cmd.Paths.WorkDir, ".githooks", HookPrepareCommitMsg))
+	require.NoError(t, err)
+
+	out := bytes.NewBuffer(nil)
+	err = cmd.HookStatus(out)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), filepath.Join(".githooks", HookPrepareCommitMsg))
+}
+
+func TestHookPrepareCommitMsg(t *testing.T) {
+	cmd := New(newWorkspace(t))
+
+	msgFile := filepath.Join(cmd.Paths.WorkDir, ".git/COMMIT_EDITMSG")
+	err := ioutil.WriteFile(msgFile, []byte("Fix the thing\n"), 0644)
+	require.NoError(t, err)
+
+	// Nothing active, nothing changes
+	err = cmd.HookPrepareCommitMsg(".git/COMMIT_EDITMSG")
+	require.NoError(t, err)
+	b, err := ioutil.ReadFile(msgFile)
+	require.NoError(t, err)
+	require.Equal(t, "Fix the thing\n", string(b))
+
+	err = cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = cmd.TemplateSet("brett")
+	require.NoError(t, err)
+
+	// Running twice only credits the coauthor once
+	for i := 0; i < 2; i++ {
+		err = cmd.HookPrepareCommitMsg(".git/COMMIT_EDITMSG")
+		require.NoError(t, err)
+	}
+	b, err = ioutil.ReadFile(msgFile)
+	require.NoError(t, err)
+	require.Equal(t, `Fix the thing
+
+Co-Authored-By: Brett Buddin <brett@buddin.org>
+`, string(b))
+}
@@ -0,0 +1,108 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/brettbuddin/partner/internal/repository"
+)
+
+const identityKey = "partner.identity"
+
+// ManifestIdentityAdd adds an email address a coauthor can be credited with
+func (c *Command) ManifestIdentityAdd(id string, ident manifest.Identity) error {
+	return c.updateManifest(func(m *manifest.Manifest) error {
+		return m.AddIdentity(id, ident)
+	})
+}
+
+// ManifestIdentityRemove removes one of a coauthor's identities by its email
+// address or label
+func (c *Command) ManifestIdentityRemove(id, emailOrLabel string) error {
+	return c.updateManifest(func(m *manifest.Manifest) error {
+		return m.RemoveIdentity(id, emailOrLabel)
+	})
+}
+
+// ManifestIdentityList lists the email addresses of coauthors, marking the
+// ones that would be credited in the current repository
+func (c *Command) ManifestIdentityList(w io.Writer, ids ...string) error {
+	m, err := c.loadManifest()
+	if err != nil {
+		return err
+	}
+	var coauthors []manifest.Coauthor
+	if len(ids) == 0 {
+		coauthors, err = m.Find(idsOf(m.Slice())...)
+	} else {
+		coauthors, err = m.Find(ids...)
+	}
+	if err != nil || len(coauthors) == 0 {
+		return err
+	}
+	selected, err := selectIdentities(c.Paths.WorkDir, coauthors)
+	if err != nil {
+		return err
+	}
+
+	tabw := tabwriter.NewWriter(w, 5, 2, 2, ' ', 0)
+	fmt.Fprintln(tabw, "  ID\tLABEL\tEMAIL\tHOSTS")
+	for i, ca := range coauthors {
+		mark := func(email string) string {
+			if email == selected[i].Email {
+				return "* "
+			}
+			return "  "
+		}
+		fmt.Fprintf(tabw, "%s%s\t%s\t%s\t%s\n", mark(ca.Email), ca.ID, "(default)", ca.Email, "-")
+		for _, ident := range ca.Identities {
+			hosts := strings.Join(ident.Hosts, ", ")
+			if hosts == "" {
+				hosts = "-"
+			}
+			fmt.Fprintf(tabw, "%s%s\t%s\t%s\t%s\n", mark(ident.Email), ca.ID, ident.Label, ident.Email, hosts)
+		}
+	}
+	return tabw.Flush()
+}
+
+// selectIdentities picks the email address each coauthor is credited with in
+// the repository containing dir. The partner.identity git configuration takes
+// precedence over the host of the repository's remote.
+func selectIdentities(dir string, coauthors []manifest.Coauthor) ([]manifest.Coauthor, error) {
+	var hasIdentities bool
+	for _, ca := range coauthors {
+		hasIdentities = hasIdentities || len(ca.Identities) > 0
+	}
+	if !hasIdentities {
+		return coauthors, nil
+	}
+
+	label, _, err := repository.Config(dir, identityKey)
+	if err != nil {
+		return nil, err
+	}
+	var host string
+	if _, err := repository.Root(dir); err == nil {
+		if host, err = repository.RemoteHost(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	selected := make([]manifest.Coauthor, len(coauthors))
+	for i, ca := range coauthors {
+		selected[i] = ca.Select(label, host)
+	}
+	return selected, nil
+}
+
+func idsOf(coauthors []manifest.Coauthor) []string {
+	var ids []string
+	for _, ca := range coauthors {
+		ids = append(ids, ca.ID)
+	}
+	return ids
+}
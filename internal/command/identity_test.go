@@ -0,0 +1,87 @@
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentities(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	runGit(t, cmd.Paths.WorkDir, "remote", "add", "origin", "git@gitlab.work.example.com:platform/partner.git")
+
+	err := cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = cmd.ManifestIdentityAdd("brett", manifest.Identity{
+		Label: "work",
+		Email: "brett@work.example.com",
+		Hosts: []string{"gitlab.work.example.com"},
+	})
+	require.NoError(t, err)
+
+	// The identity matching the remote is credited
+	err = cmd.TemplateSet("brett")
+	require.NoError(t, err)
+	repoPaths, err := cmd.Paths.Repository()
+	require.NoError(t, err)
+	b, err := ioutil.ReadFile(repoPaths.TemplateFile)
+	require.NoError(t, err)
+	require.Contains(t, string(b), "Co-Authored-By: Brett Buddin <brett@work.example.com>\n")
+
+	out := bytes.NewBuffer(nil)
+	err = cmd.ManifestIdentityList(out)
+	require.NoError(t, err)
+	require.Equal(t, `  ID     LABEL      EMAIL                   HOSTS
+  brett  (default)  brett@buddin.org        -
+* brett  work       brett@work.example.com  gitlab.work.example.com
+`, out.String())
+
+	// An identity can be chosen for the repository, whatever its remote
+	runGit(t, cmd.Paths.WorkDir, "config", "partner.identity", "personal")
+	err = cmd.ManifestIdentityAdd("brett", manifest.Identity{Label: "personal", Email: "brett@personal.example.com"})
+	require.NoError(t, err)
+	msg := commitMessageFromTemplate(t, cmd, repoPaths.TemplateFile)
+	require.Contains(t, msg, "Co-Authored-By: Brett Buddin <brett@personal.example.com>\n")
+	require.NotContains(t, msg, "brett@work.example.com")
+
+	// Someone already credited under another address isn't credited again
+	msgFile := filepath.Join(repoPaths.GitDir, "COMMIT_EDITMSG")
+	err = ioutil.WriteFile(msgFile, []byte("Fix the thing\n\nCo-Authored-By: Brett <brett@buddin.org>\n"), 0644)
+	require.NoError(t, err)
+	err = cmd.HookPrepareCommitMsg(".git/COMMIT_EDITMSG")
+	require.NoError(t, err)
+	b, err = ioutil.ReadFile(msgFile)
+	require.NoError(t, err)
+	require.Equal(t, "Fix the thing\n\nCo-Authored-By: Brett <brett@buddin.org>\n", string(b))
+}
+
+func TestIdentities_Global(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	runGit(t, cmd.Paths.WorkDir, "remote", "add", "origin", "git@gitlab.work.example.com:platform/partner.git")
+
+	err := cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = cmd.ManifestIdentityAdd("brett", manifest.Identity{
+		Label: "work",
+		Email: "brett@work.example.com",
+		Hosts: []string{"gitlab.work.example.com"},
+	})
+	require.NoError(t, err)
+
+	// The global template is used by every repository, not only the one it
+	// was set in
+	err = cmd.TemplateSetGlobal("brett")
+	require.NoError(t, err)
+	b, err := ioutil.ReadFile(cmd.Paths.GlobalTemplateFile)
+	require.NoError(t, err)
+	require.Contains(t, string(b), "Co-Authored-By: Brett Buddin <brett@buddin.org>\n")
+
+	// The hook still credits the identity for the repository
+	msg := commitMessageFromTemplate(t, cmd, cmd.Paths.GlobalTemplateFile)
+	require.Contains(t, msg, "Co-Authored-By: Brett Buddin <brett@work.example.com>\n")
+	require.NotContains(t, msg, "brett@buddin.org")
+}
@@ -0,0 +1,168 @@
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/brettbuddin/partner/internal/repository"
+	"github.com/brettbuddin/partner/internal/trailer"
+)
+
+// importLogFormat lists the author of a commit, followed by the people
+// credited in its Co-Authored-By trailers, separated by unit separators
+const importLogFormat = "%an <%ae>%x1f%(trailers:key=" + trailer.CoAuthoredBy + ",valueonly,unfold,separator=%x1f)"
+
+// noreplyPrefix matches the account ID GitHub and GitLab put in front of the
+// username in noreply email addresses
+var noreplyPrefix = regexp.MustCompile(`^\d+[+-]`)
+
+// invalidIDChars matches characters left out of generated IDs
+var invalidIDChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ManifestImportGit proposes adding everyone who authored or coauthored
+// commits in the current repository to the user's manifest, optionally only
+// since a date git understands (e.g. "6.months"). People are recognized by
+// email address; those already in the manifest, and the current git user, are
+// skipped. Each proposal is confirmed on in, unless yes is set.
+func (c *Command) ManifestImportGit(in io.Reader, w io.Writer, yes bool, since string) error {
+	repoPaths, err := c.Paths.Repository()
+	if err != nil {
+		return err
+	}
+	m, err := c.loadManifest()
+	if err != nil {
+		return err
+	}
+
+	var args []string
+	if since != "" {
+		args = append(args, "--since="+since)
+	}
+	records, err := repository.Log(repoPaths.Root, importLogFormat, args...)
+	if err != nil {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+
+	known := map[string]bool{}
+	for _, ca := range m.Coauthors {
+		for _, email := range ca.Emails() {
+			known[strings.ToLower(email)] = true
+		}
+	}
+	if self, ok, err := repository.Config(repoPaths.Root, "user.email"); err != nil {
+		return err
+	} else if ok {
+		known[strings.ToLower(self)] = true
+	}
+
+	// History is listed newest first, so the name someone committed under
+	// most recently is the one they're proposed with.
+	var (
+		proposals []manifest.Coauthor
+		ids       = map[string]bool{}
+	)
+	for _, record := range records {
+		for _, person := range strings.Split(record, "\x1f") {
+			name, email, ok := parsePerson(person)
+			if !ok || known[strings.ToLower(email)] {
+				continue
+			}
+			known[strings.ToLower(email)] = true
+			id := generateID(m, ids, email)
+			ids[strings.ToLower(id)] = true
+			proposals = append(proposals, manifest.Coauthor{
+				ID:    id,
+				Name:  name,
+				Email: email,
+				Type:  manifest.CoauthorTypeGit,
+			})
+		}
+	}
+	if len(proposals) == 0 {
+		fmt.Fprintln(w, "No new coauthors found in the history")
+		return nil
+	}
+	sort.Slice(proposals, func(i, j int) bool {
+		return strings.ToLower(proposals[i].ID) < strings.ToLower(proposals[j].ID)
+	})
+
+	var (
+		accepted []manifest.Coauthor
+		answers  = bufio.NewReader(in)
+	)
+	for _, ca := range proposals {
+		if !yes {
+			fmt.Fprintf(w, "Add %s <%s> as %s? [y/N] ", ca.Name, ca.Email, ca.ID)
+			answer, _ := answers.ReadString('\n')
+			answer = strings.ToLower(strings.TrimSpace(answer))
+			if answer != "y" && answer != "yes" {
+				continue
+			}
+		}
+		accepted = append(accepted, ca)
+	}
+	if len(accepted) == 0 {
+		fmt.Fprintln(w, "No coauthors added")
+		return nil
+	}
+
+	err = c.updateManifest(func(m *manifest.Manifest) error {
+		return m.Add(accepted...)
+	})
+	if err != nil {
+		return err
+	}
+	for _, ca := range accepted {
+		fmt.Fprintf(w, "Added %s (%s <%s>)\n", ca.ID, ca.Name, ca.Email)
+	}
+	return nil
+}
+
+// parsePerson splits an author or trailer value, "Name <email>", into its
+// parts. These aren't RFC 5322 addresses: names such as "Last, First" or
+// "Foo (Bar)" are taken as they are. Someone without a name is named after the
+// local part of their email address.
+func parsePerson(s string) (name, email string, ok bool) {
+	s = strings.TrimSpace(s)
+	lt := strings.LastIndex(s, "<")
+	if lt < 0 || !strings.HasSuffix(s, ">") {
+		return "", "", false
+	}
+	name, email = strings.TrimSpace(s[:lt]), strings.TrimSpace(s[lt+1:len(s)-1])
+	i := strings.LastIndex(email, "@")
+	if i <= 0 || i == len(email)-1 {
+		return "", "", false
+	}
+	if name == "" {
+		name = email[:i]
+	}
+	return name, email, true
+}
+
+// generateID derives an ID from an email address that isn't an ID or alias in
+// the manifest yet, nor taken by another proposal. The username is used for
+// GitHub and GitLab noreply addresses, and the local part for any other.
+func generateID(m *manifest.Manifest, taken map[string]bool, email string) string {
+	i := strings.LastIndex(email, "@")
+	local, domain := email[:i], strings.ToLower(email[i+1:])
+	if strings.HasPrefix(domain, "users.noreply.") {
+		local = noreplyPrefix.ReplaceAllString(local, "")
+	}
+	base := strings.Trim(invalidIDChars.ReplaceAllString(local, "-"), "-")
+	if base == "" {
+		base = "coauthor"
+	}
+
+	id := base
+	for n := 2; ; n++ {
+		if _, ok := m.Lookup(id); !ok && !taken[strings.ToLower(id)] {
+			return id
+		}
+		id = fmt.Sprintf("%s%d", base, n)
+	}
+}
@@ -0,0 +1,87 @@
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestImportGit(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	dir := cmd.Paths.WorkDir
+	commitAs(t, dir, "Initial commit")
+	runGit(t, dir, "-c", "user.name=George", "-c", "user.email=1253326+GeorgeMac@users.noreply.github.com",
+		"commit", "--allow-empty", "-m", "Add feature\n\nCo-Authored-By: Stuart Carnie <stuart@example.com>\nCo-Authored-By: Gavin Cabbage <gavin@example.com>")
+	runGit(t, dir, "-c", "user.name=Stuart Carnie", "-c", "user.email=Stuart@example.com",
+		"commit", "--allow-empty", "-m", "Fix feature\n\nCo-authored-by: Person A <a@buddin.org>")
+
+	// Known coauthors aren't proposed, and generated IDs don't collide with
+	// existing ones
+	err := cmd.ManifestAdd("persona", "Person A", "a@buddin.org")
+	require.NoError(t, err)
+	err = cmd.ManifestAdd("gavin", "Gavin", "gavin@buddin.org")
+	require.NoError(t, err)
+
+	out := bytes.NewBuffer(nil)
+	err = cmd.ManifestImportGit(strings.NewReader("y\nn\ny\n"), out, false, "")
+	require.NoError(t, err)
+	require.Equal(t, "Add Gavin Cabbage <gavin@example.com> as gavin2? [y/N] "+
+		"Add George <1253326+GeorgeMac@users.noreply.github.com> as GeorgeMac? [y/N] "+
+		"Add Stuart Carnie <Stuart@example.com> as Stuart? [y/N] "+
+		"Added gavin2 (Gavin Cabbage <gavin@example.com>)\n"+
+		"Added Stuart (Stuart Carnie <Stuart@example.com>)\n", out.String())
+
+	m, err := manifest.Load(cmd.Paths.ManifestFile)
+	require.NoError(t, err)
+	require.Equal(t, manifest.Coauthor{
+		ID:    "Stuart",
+		Name:  "Stuart Carnie",
+		Email: "Stuart@example.com",
+		Type:  manifest.CoauthorTypeGit,
+	}, m.Coauthors["stuart"])
+	_, ok := m.Coauthors["georgemac"]
+	require.False(t, ok)
+
+	// Rejected coauthors are proposed again
+	out.Reset()
+	err = cmd.ManifestImportGit(nil, out, true, "")
+	require.NoError(t, err)
+	require.Equal(t, "Added GeorgeMac (George <1253326+GeorgeMac@users.noreply.github.com>)\n", out.String())
+
+	out.Reset()
+	err = cmd.ManifestImportGit(nil, out, true, "")
+	require.NoError(t, err)
+	require.Equal(t, "No new coauthors found in the history\n", out.String())
+
+	out.Reset()
+	err = cmd.ManifestImportGit(nil, out, true, "2999-01-01")
+	require.NoError(t, err)
+	require.Equal(t, "No new coauthors found in the history\n", out.String())
+}
+
+func TestParsePerson(t *testing.T) {
+	tests := []struct {
+		person string
+		name   string
+		email  string
+		ok     bool
+	}{
+		{person: "Stuart Carnie <stuart@example.com>", name: "Stuart Carnie", email: "stuart@example.com", ok: true},
+		{person: "Carnie, Stuart <stuart@example.com>", name: "Carnie, Stuart", email: "stuart@example.com", ok: true},
+		{person: "Foo (Bar) <foo@example.com>", name: "Foo (Bar)", email: "foo@example.com", ok: true},
+		{person: " <foo@example.com>", name: "foo", email: "foo@example.com", ok: true},
+		{person: "Foo <foo>", ok: false},
+		{person: "Foo", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.person, func(t *testing.T) {
+			name, email, ok := parsePerson(tt.person)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.name, name)
+			require.Equal(t, tt.email, email)
+		})
+	}
+}
@@ -1,41 +1,591 @@
 package command
 
 import (
+	"fmt"
 	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
 
+	"github.com/brettbuddin/partner/internal/lockedfile"
 	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/brettbuddin/partner/internal/repository"
+	"github.com/brettbuddin/partner/internal/state"
 )
 
-// ManifestList lists all coauthors
-func (c *Command) ManifestList(w io.Writer) error {
-	m, err := manifest.Load(c.Paths.ManifestFile)
+// ManifestList lists all coauthors. With showSource, the manifest each one
+// comes from is listed as well. Aliases are listed when any coauthor has them.
+func (c *Command) ManifestList(w io.Writer, showSource bool) error {
+	m, err := c.loadManifest()
 	if err != nil {
 		return err
 	}
 	if len(m.Coauthors) == 0 {
 		return nil
 	}
-	return writeList(w, m.Slice()...)
+	showAliases := false
+	for _, ca := range m.Coauthors {
+		showAliases = showAliases || len(ca.Aliases) > 0
+	}
+	if !showSource && !showAliases {
+		return writeList(w, m.Slice()...)
+	}
+
+	coauthors := m.Slice()
+	sort.Slice(coauthors, func(i, j int) bool {
+		return strings.ToLower(coauthors[i].ID) < strings.ToLower(coauthors[j].ID)
+	})
+	tabw := tabwriter.NewWriter(w, 5, 2, 2, ' ', 0)
+	header := "ID\tNAME\tEMAIL\tTYPE"
+	if showAliases {
+		header += "\tALIASES"
+	}
+	if showSource {
+		header += "\tSOURCE"
+	}
+	fmt.Fprintln(tabw, header)
+	for _, ca := range coauthors {
+		row := fmt.Sprintf("%s\t%s\t%s\t%s", ca.ID, ca.Name, ca.Email, ca.Type)
+		if showAliases {
+			aliases := strings.Join(ca.Aliases, ", ")
+			if aliases == "" {
+				aliases = "-"
+			}
+			row += "\t" + aliases
+		}
+		if showSource {
+			row += "\t" + ca.Source
+		}
+		fmt.Fprintln(tabw, row)
+	}
+	return tabw.Flush()
+}
+
+// ManifestListIDs lists the IDs and aliases of all coauthors, one per line.
+// It's meant for shell completion.
+func (c *Command) ManifestListIDs(w io.Writer) error {
+	m, err := c.loadManifest()
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, ca := range m.Coauthors {
+		ids = append(ids, ca.ID)
+		ids = append(ids, ca.Aliases...)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return strings.ToLower(ids[i]) < strings.ToLower(ids[j])
+	})
+	for _, id := range ids {
+		fmt.Fprintln(w, id)
+	}
+	return nil
+}
+
+// loadManifest reads the coauthors available in the working directory: those
+// in the user's manifest, overlaid with the manifest committed at the root of
+// the repository, if there is one.
+//
+// Changes are only ever written to the user's manifest, which must be loaded
+// on its own for that.
+func (c *Command) loadManifest() (*manifest.Manifest, error) {
+	return manifest.LoadLayers(c.manifestLayers()...)
+}
+
+func (c *Command) manifestLayers() []manifest.Layer {
+	layers := []manifest.Layer{
+		{Source: manifest.SourceUser, Path: c.Paths.ManifestFile},
+	}
+	if root, err := repository.Root(c.Paths.WorkDir); err == nil {
+		layers = append(layers, manifest.Layer{
+			Source: manifest.SourceRepository,
+			Path:   filepath.Join(root, manifest.RepositoryFile),
+		})
+	}
+	return layers
+}
+
+// ManifestMigrate upgrades the user's manifest and the repository's manifest
+// to the current manifest version. With check, nothing is written and an
+// error is returned if any manifest is not at the current version.
+func (c *Command) ManifestMigrate(w io.Writer, check bool) error {
+	var outdated []string
+	for _, layer := range c.manifestLayers() {
+		if _, err := os.Stat(layer.Path); os.IsNotExist(err) {
+			continue
+		}
+		version, err := manifest.FileVersion(layer.Path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", layer.Path, err)
+		}
+
+		switch {
+		case version > manifest.CurrentVersion:
+			return manifest.NewerVersionError{Path: layer.Path, Version: version}
+		case version == manifest.CurrentVersion:
+			fmt.Fprintf(w, "%s: up to date (version %d)\n", layer.Path, version)
+		case check:
+			fmt.Fprintf(w, "%s: needs migration from version %d to %d\n", layer.Path, version, manifest.CurrentVersion)
+			outdated = append(outdated, layer.Path)
+		default:
+			if err := migrateManifest(layer.Path); err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%s: migrated from version %d to %d\n", layer.Path, version, manifest.CurrentVersion)
+		}
+	}
+	if len(outdated) > 0 {
+		return fmt.Errorf("%d manifest(s) need migration; run partner manifest migrate", len(outdated))
+	}
+	return nil
+}
+
+func migrateManifest(path string) error {
+	unlock, err := lockedfile.Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	m, err := manifest.Load(path)
+	if err != nil {
+		return err
+	}
+	return manifest.WriteFile(path, m)
+}
+
+// ManifestRemove removes a coauthor from the Manifest. The groups they
+// belonged to are updated, and they are dropped from sessions in the current
+// repository, the global session and the current repository's branch sets,
+// all of which are listed on w.
+func (c *Command) ManifestRemove(w io.Writer, ids ...string) error {
+	layered, err := c.loadManifest()
+	if err != nil {
+		return err
+	}
+	var (
+		groups  = map[string][]string{}
+		removed = map[string]string{}
+		m       *manifest.Manifest
+	)
+	err = c.updateManifest(func(user *manifest.Manifest) error {
+		for _, id := range ids {
+			ca, ok := user.Lookup(id)
+			if shared, found := layered.Lookup(id); !ok && found {
+				return fmt.Errorf("coauthor %q is defined in the %s manifest and cannot be removed here", shared.ID, shared.Source)
+			}
+			groups[id] = user.GroupsContaining(ca.ID)
+			removed[id] = ca.ID
+		}
+		m = user
+		return user.Remove(ids...)
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		for _, name := range groups[id] {
+			if _, ok := m.Groups[strings.ToLower(name)]; ok {
+				fmt.Fprintf(w, "Removed %s from group %s\n", id, name)
+			} else {
+				fmt.Fprintf(w, "Removed group %s, which has no members left\n", name)
+			}
+		}
+	}
+
+	// A coauthor may still be defined by the repository's manifest
+	if layered, err = c.loadManifest(); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, ok := layered.Lookup(removed[id]); ok {
+			continue
+		}
+		if err := c.followRemove(w, removed[id]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// ManifestRemove removes a coauthor from the Manifest
-func (c *Command) ManifestRemove(ids ...string) error {
+// followRemove drops a removed coauthor from active sessions and branch sets,
+// which would otherwise refer to a coauthor that no longer exists.
+func (c *Command) followRemove(w io.Writer, id string) error {
+	sessions := []TemplatePaths{c.Paths.Global()}
+	if repoPaths, err := c.Paths.Repository(); err == nil {
+		sets, err := branchSets(repoPaths.Root)
+		if err != nil {
+			return err
+		}
+		for _, branch := range sortedBranches(sets) {
+			ids, found := dropID(sets[branch], id)
+			if !found {
+				continue
+			}
+			if len(ids) == 0 {
+				err = repository.UnsetConfig(repoPaths.Root, repository.ScopeLocal, branchKey(branch))
+			} else {
+				err = repository.SetConfig(repoPaths.Root, repository.ScopeLocal, branchKey(branch), strings.Join(ids, ","))
+			}
+			if err != nil {
+				return fmt.Errorf("failed to update coauthors linked to branch %q: %w", branch, err)
+			}
+			fmt.Fprintf(w, "Removed %s from branch %s\n", id, branch)
+		}
+		sessions = append(sessions, repoPaths.TemplatePaths)
+	}
+	for _, tmplPaths := range sessions {
+		dropped, err := c.dropFromSession(tmplPaths, id)
+		if err != nil {
+			return err
+		}
+		if dropped {
+			fmt.Fprintf(w, "Removed %s from the %s session\n", id, scopeName(tmplPaths.Scope))
+		}
+	}
+	return nil
+}
+
+// dropFromSession removes a coauthor from an active session and regenerates
+// its commit template, reporting whether they were part of it. A session left
+// without coauthors is cleared.
+func (c *Command) dropFromSession(tmplPaths TemplatePaths, id string) (bool, error) {
+	unlock, err := lockedfile.Lock(tmplPaths.StateFile)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	s, err := loadSession(tmplPaths)
+	if err != nil || s == nil {
+		return false, err
+	}
+	ids, found := dropID(s.IDs, id)
+	if !found {
+		return false, nil
+	}
+	if len(ids) == 0 {
+		return true, clearSession(tmplPaths)
+	}
+	s.IDs = ids
+	if err := state.WriteFile(tmplPaths.StateFile, s); err != nil {
+		return false, fmt.Errorf("failed to write state: %w", err)
+	}
+
+	m, err := c.loadManifest()
+	if err != nil {
+		return false, err
+	}
+	coauthors, err := m.Find(s.IDs...)
+	if err != nil {
+		return false, err
+	}
+	return true, c.writeTemplate(tmplPaths, s, coauthors)
+}
+
+// ManifestEdit changes a coauthor in the user's manifest. Sessions in the
+// current repository and the global session, along with the current
+// repository's branch sets, follow a change of ID and have their commit
+// templates regenerated.
+func (c *Command) ManifestEdit(id string, ed manifest.Edit) error {
+	layered, err := c.loadManifest()
+	if err != nil {
+		return err
+	}
+	var original, edited manifest.Coauthor
+	err = c.updateManifest(func(m *manifest.Manifest) error {
+		var ok bool
+		original, ok = m.Lookup(id)
+		if shared, found := layered.Lookup(id); !ok && found {
+			return fmt.Errorf("coauthor %q is defined in the %s manifest and cannot be edited here", shared.ID, shared.Source)
+		}
+		edited, err = m.Edit(id, ed)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return c.followEdit(original.ID, edited.ID)
+}
+
+// followEdit brings active sessions and branch sets up to date with a
+// coauthor that was changed, and possibly renamed.
+func (c *Command) followEdit(oldID, newID string) error {
+	sessions := []TemplatePaths{c.Paths.Global()}
+	if repoPaths, err := c.Paths.Repository(); err == nil {
+		if err := renameInBranchSets(repoPaths.Root, oldID, newID); err != nil {
+			return err
+		}
+		sessions = append(sessions, repoPaths.TemplatePaths)
+	}
+	for _, tmplPaths := range sessions {
+		if err := c.refreshSession(tmplPaths, oldID, newID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshSession renames a coauthor in an active session and regenerates its
+// commit template. Sessions the coauthor isn't part of are left alone.
+func (c *Command) refreshSession(tmplPaths TemplatePaths, oldID, newID string) error {
+	unlock, err := lockedfile.Lock(tmplPaths.StateFile)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s, err := loadSession(tmplPaths)
+	if err != nil || s == nil {
+		return err
+	}
+	ids, renamed := renameID(s.IDs, oldID, newID)
+	if !renamed {
+		return nil
+	}
+	s.IDs = ids
+	if err := state.WriteFile(tmplPaths.StateFile, s); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+
+	m, err := c.loadManifest()
+	if err != nil {
+		return err
+	}
+	coauthors, err := m.Find(s.IDs...)
+	if err != nil {
+		return err
+	}
+	return c.writeTemplate(tmplPaths, s, coauthors)
+}
+
+func renameInBranchSets(dir, oldID, newID string) error {
+	sets, err := branchSets(dir)
+	if err != nil {
+		return err
+	}
+	for _, branch := range sortedBranches(sets) {
+		ids, renamed := renameID(sets[branch], oldID, newID)
+		if !renamed || oldID == newID {
+			continue
+		}
+		if err := repository.SetConfig(dir, repository.ScopeLocal, branchKey(branch), strings.Join(ids, ",")); err != nil {
+			return fmt.Errorf("failed to update coauthors linked to branch %q: %w", branch, err)
+		}
+	}
+	return nil
+}
+
+// renameID replaces an ID in a list, reporting whether it was found
+func renameID(ids []string, oldID, newID string) ([]string, bool) {
+	var (
+		out   []string
+		found bool
+	)
+	for _, id := range ids {
+		if strings.EqualFold(id, oldID) {
+			id, found = newID, true
+		}
+		out = append(out, id)
+	}
+	return out, found
+}
+
+// dropID removes an ID from a list, reporting whether it was found
+func dropID(ids []string, drop string) ([]string, bool) {
+	var (
+		out   []string
+		found bool
+	)
+	for _, id := range ids {
+		if strings.EqualFold(id, drop) {
+			found = true
+			continue
+		}
+		out = append(out, id)
+	}
+	return out, found
+}
+
+// ManifestGroupList lists all groups and their members
+func (c *Command) ManifestGroupList(w io.Writer) error {
+	m, err := c.loadManifest()
+	if err != nil {
+		return err
+	}
+	groups := m.GroupSlice()
+	if len(groups) == 0 {
+		return nil
+	}
+
+	tabw := tabwriter.NewWriter(w, 5, 2, 2, ' ', 0)
+	fmt.Fprintln(tabw, "GROUP\tMEMBERS")
+	for _, g := range groups {
+		fmt.Fprintf(tabw, "%s%s\t%s\n", manifest.GroupPrefix, g.Name, strings.Join(g.Members, ", "))
+	}
+	return tabw.Flush()
+}
+
+// ManifestGroupAdd adds coauthors to a group, creating it if necessary.
+// Members may come from the repository's manifest as well as the user's.
+func (c *Command) ManifestGroupAdd(name string, ids ...string) error {
+	layered, err := c.loadManifest()
+	if err != nil {
+		return err
+	}
+	return c.updateManifest(func(m *manifest.Manifest) error {
+		return m.AddToGroupFrom(layered, groupName(name), ids...)
+	})
+}
+
+// ManifestGroupSet replaces the members of a group, creating it if necessary.
+// Members may come from the repository's manifest as well as the user's.
+func (c *Command) ManifestGroupSet(name string, ids ...string) error {
+	layered, err := c.loadManifest()
+	if err != nil {
+		return err
+	}
+	return c.updateManifest(func(m *manifest.Manifest) error {
+		return m.SetGroupFrom(layered, groupName(name), ids...)
+	})
+}
+
+// ManifestGroupRemove removes coauthors from a group. Without any IDs the
+// group itself is removed.
+func (c *Command) ManifestGroupRemove(name string, ids ...string) error {
+	return c.updateManifest(func(m *manifest.Manifest) error {
+		if len(ids) == 0 {
+			return m.RemoveGroups(groupName(name))
+		}
+		return m.RemoveFromGroup(groupName(name), ids...)
+	})
+}
+
+// ManifestAlias gives a coauthor aliases to refer to them by
+func (c *Command) ManifestAlias(id string, aliases ...string) error {
+	return c.updateManifest(func(m *manifest.Manifest) error {
+		return m.AddAlias(id, aliases...)
+	})
+}
+
+// ManifestUnalias removes aliases
+func (c *Command) ManifestUnalias(aliases ...string) error {
+	return c.updateManifest(func(m *manifest.Manifest) error {
+		return m.RemoveAlias(aliases...)
+	})
+}
+
+// updateManifest applies changes to the user's manifest. Other partner
+// processes are kept from changing the manifest between it being read and
+// written back. The manifest isn't written while any of its aliases conflict
+// with a coauthor in another layer, whether the changes introduced the
+// conflict or it was already there and the changes don't fix it.
+func (c *Command) updateManifest(fn func(*manifest.Manifest) error) error {
+	unlock, err := lockedfile.Lock(c.Paths.ManifestFile)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	m, err := manifest.Load(c.Paths.ManifestFile)
 	if err != nil {
 		return err
 	}
-	if err := m.Remove(ids...); err != nil {
+	if err := fn(m); err != nil {
+		return err
+	}
+	layers := c.manifestLayers()
+	layers[0].Manifest = m
+	if err := manifest.CheckAliases(layers...); err != nil {
 		return err
 	}
 	return manifest.WriteFile(c.Paths.ManifestFile, m)
 }
 
+// groupName accepts group names with or without the prefix used to activate
+// them
+func groupName(name string) string {
+	return strings.TrimPrefix(name, manifest.GroupPrefix)
+}
+
 // UserFetcher fetches coauthor information from somewhere else
 type UserFetcher interface {
 	Fetch(username string) (manifest.Coauthor, error)
 }
 
-// ManifestFetchAdd adds a coauthor by looking up their information remotely
+// UserRefresher fetches up to date information for a coauthor that was
+// fetched before
+type UserRefresher interface {
+	Refresh(ca manifest.Coauthor) (manifest.Coauthor, error)
+}
+
+// TokenSource finds the API token to authenticate requests to a host with.
+// An empty token means requests are made anonymously.
+type TokenSource interface {
+	Token(host string) (string, error)
+}
+
+// authorize adds the token for a host to a request as a bearer token
+func authorize(r *http.Request, tokens TokenSource, host string) error {
+	if tokens == nil {
+		return nil
+	}
+	token, err := tokens.Token(host)
+	if err != nil {
+		return fmt.Errorf("failed to find a token for %s: %w", host, err)
+	}
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// LoginSource finds the username and password to authenticate requests to a
+// host with. An empty password means requests are made anonymously.
+type LoginSource interface {
+	Login(host string) (username, password string, err error)
+}
+
+// authorizeLogin adds the login for a host to a request. Passwords found
+// without a username, such as access tokens in environment variables, are
+// sent as bearer tokens; the rest use basic auth.
+func authorizeLogin(r *http.Request, logins LoginSource, host string) error {
+	if logins == nil {
+		return nil
+	}
+	username, password, err := logins.Login(host)
+	if err != nil {
+		return fmt.Errorf("failed to find a password for %s: %w", host, err)
+	}
+	switch {
+	case password == "":
+	case username == "":
+		r.Header.Set("Authorization", "Bearer "+password)
+	default:
+		r.SetBasicAuth(username, password)
+	}
+	return nil
+}
+
+// UserNotFoundError is returned by fetchers when a user doesn't exist
+type UserNotFoundError struct {
+	Username string
+	Service  string
+	Message  string
+}
+
+func (e UserNotFoundError) Error() string {
+	return fmt.Sprintf("error fetching %q from %s: %s", e.Username, e.Service, e.Message)
+}
+
+// ManifestFetchAdd adds a coauthor by looking up their information remotely.
+// When a coauthor from another instance of the service already has the same
+// ID, the new coauthor's ID is qualified with their host, e.g.
+// "github.example.com/brettbuddin". Aliases suggested by the fetcher are
+// added when no other coauthor uses them.
 func (c *Command) ManifestFetchAdd(fetcher UserFetcher, usernames ...string) error {
 	var coauthors []manifest.Coauthor
 	for _, username := range usernames {
@@ -46,30 +596,70 @@ func (c *Command) ManifestFetchAdd(fetcher UserFetcher, usernames ...string) err
 		coauthors = append(coauthors, coauthor)
 	}
 
-	m, err := manifest.Load(c.Paths.ManifestFile)
-	if err != nil {
-		return err
+	return c.updateManifest(func(m *manifest.Manifest) error {
+		for _, ca := range coauthors {
+			existing, ok := m.Lookup(ca.ID)
+			if ok && (existing.Type != ca.Type || coauthorHost(existing) != coauthorHost(ca)) {
+				ca.ID = coauthorHost(ca) + "/" + ca.ID
+			}
+			aliases := ca.Aliases
+			ca.Aliases = nil
+			if err := m.Add(ca); err != nil {
+				return err
+			}
+			// Aliases suggested by the fetcher are only added while they're
+			// free
+			for _, alias := range aliases {
+				m.AddAlias(ca.ID, alias)
+			}
+		}
+		return nil
+	})
+}
+
+// publicHosts are the hosts of the public instances of services coauthors are
+// fetched from
+var publicHosts = map[string]string{
+	manifest.CoauthorTypeGitHub:    githubHost,
+	manifest.CoauthorTypeGitLab:    gitlabHost,
+	manifest.CoauthorTypeBitbucket: bitbucketHost,
+	manifest.CoauthorTypeGitea:     giteaHost,
+}
+
+// coauthorHost returns the host of the instance a coauthor was fetched from
+func coauthorHost(ca manifest.Coauthor) string {
+	if ca.Host == "" {
+		return publicHosts[ca.Type]
 	}
-	if err := m.Add(coauthors...); err != nil {
-		return err
+	return strings.ToLower(ca.Host)
+}
+
+// coauthorLogin returns the username a coauthor was fetched with, without the
+// host their ID may have been qualified with by ManifestFetchAdd
+func coauthorLogin(ca manifest.Coauthor) string {
+	prefix := coauthorHost(ca) + "/"
+	if len(ca.ID) > len(prefix) && strings.EqualFold(ca.ID[:len(prefix)], prefix) {
+		return ca.ID[len(prefix):]
 	}
-	return manifest.WriteFile(c.Paths.ManifestFile, m)
+	return ca.ID
+}
+
+// DefaultHost returns the instance of a service coauthors are fetched from
+// when none is given, according to the partner.<type>Host git configuration
+// (e.g. partner.githubHost). An empty host means the public instance.
+func DefaultHost(dir, coauthorType string) (string, error) {
+	host, _, err := repository.Config(dir, "partner."+coauthorType+"Host")
+	return host, err
 }
 
 // ManifestAdd adds a coauthor using manually entered information
 func (c *Command) ManifestAdd(id, name, email string) error {
-	m, err := manifest.Load(c.Paths.ManifestFile)
-	if err != nil {
-		return err
-	}
-	err = m.Add(manifest.Coauthor{
-		ID:    id,
-		Name:  name,
-		Email: email,
-		Type:  manifest.CoauthorTypeManual,
+	return c.updateManifest(func(m *manifest.Manifest) error {
+		return m.Add(manifest.Coauthor{
+			ID:    id,
+			Name:  name,
+			Email: email,
+			Type:  manifest.CoauthorTypeManual,
+		})
 	})
-	if err != nil {
-		return err
-	}
-	return manifest.WriteFile(c.Paths.ManifestFile, m)
 }
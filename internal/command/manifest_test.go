@@ -2,6 +2,9 @@ package command
 
 import (
 	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	"github.com/brettbuddin/partner/internal/manifest"
@@ -29,6 +32,8 @@ func TestManifestFetchAdd(t *testing.T) {
 	require.Equal(t, listExample(`
 ID           NAME          EMAIL                                      TYPE
 brettbuddin  Brett Buddin  6059+brettbuddin@users.noreply.github.com  github
+
+Scope: repository
 `), out.String())
 }
 
@@ -40,3 +45,359 @@ type fetcher struct {
 func (f fetcher) Fetch(username string) (manifest.Coauthor, error) {
 	return f.coauthor, f.err
 }
+
+func TestManifestGroups(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	err := cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = cmd.ManifestAdd("persona", "Person A", "a@buddin.org")
+	require.NoError(t, err)
+
+	err = cmd.ManifestGroupAdd("mob", "brett", "persona")
+	require.NoError(t, err)
+
+	out := bytes.NewBuffer(nil)
+	err = cmd.ManifestGroupList(out)
+	require.NoError(t, err)
+	require.Equal(t, listExample(`
+GROUP  MEMBERS
+@mob   brett, persona
+`), out.String())
+
+	// Activating the group activates its members
+	err = cmd.TemplateSet("@mob")
+	require.NoError(t, err)
+	out.Reset()
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Equal(t, listExample(`
+ID       NAME          EMAIL             TYPE
+brett    Brett Buddin  brett@buddin.org  manual
+persona  Person A      a@buddin.org      manual
+
+Scope: repository
+`), out.String())
+
+	// Removing a coauthor reports the groups and sessions that changed
+	out.Reset()
+	err = cmd.ManifestRemove(out, "persona")
+	require.NoError(t, err)
+	require.Equal(t, "Removed persona from group mob\n"+
+		"Removed persona from the repository session\n", out.String())
+
+	out.Reset()
+	err = cmd.ManifestRemove(out, "brett")
+	require.NoError(t, err)
+	require.Equal(t, "Removed group mob, which has no members left\n"+
+		"Removed brett from the repository session\n", out.String())
+
+	err = cmd.ManifestGroupRemove("@mob")
+	require.Error(t, err)
+}
+
+func TestManifestRemove_ActiveCoauthors(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	runGit(t, cmd.Paths.WorkDir, "checkout", "-q", "-b", "main")
+	err := cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = cmd.ManifestAdd("persona", "Person A", "a@buddin.org")
+	require.NoError(t, err)
+	err = cmd.TemplateSet("brett", "persona")
+	require.NoError(t, err)
+	err = cmd.TemplateSetGlobal("persona")
+	require.NoError(t, err)
+	err = cmd.TemplateSetBranch("persona")
+	require.NoError(t, err)
+
+	// Coauthors who no longer exist are dropped wherever they are active, so
+	// that the hook doesn't trip over them
+	out := bytes.NewBuffer(nil)
+	err = cmd.ManifestRemove(out, "persona")
+	require.NoError(t, err)
+	require.Equal(t, "Removed persona from branch main\n"+
+		"Removed persona from the global session\n"+
+		"Removed persona from the repository session\n", out.String())
+
+	out.Reset()
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Equal(t, listExample(`
+ID     NAME          EMAIL             TYPE
+brett  Brett Buddin  brett@buddin.org  manual
+
+Scope: repository
+`), out.String())
+	repoPaths, err := cmd.Paths.Repository()
+	require.NoError(t, err)
+	msg := commitMessageFromTemplate(t, cmd, repoPaths.TemplateFile)
+	require.Contains(t, msg, "Co-Authored-By: Brett Buddin <brett@buddin.org>\n")
+	require.NotContains(t, msg, "a@buddin.org")
+}
+
+func TestManifestList_RepositoryManifest(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	err := cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = cmd.ManifestAdd("persona", "Person A", "a@personal.org")
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(filepath.Join(cmd.Paths.WorkDir, ".partner.json"), []byte(`{
+  "coauthors": {
+    "persona": {"id": "persona", "type": "manual", "name": "Person A", "email": "a@buddin.org"},
+    "personb": {"id": "personb", "type": "manual", "name": "Person B", "email": "b@buddin.org"}
+  }
+}`), 0644)
+	require.NoError(t, err)
+
+	out := bytes.NewBuffer(nil)
+	err = cmd.ManifestList(out, true)
+	require.NoError(t, err)
+	require.Equal(t, listExample(`
+ID       NAME          EMAIL             TYPE    SOURCE
+brett    Brett Buddin  brett@buddin.org  manual  user
+persona  Person A      a@buddin.org      manual  repository
+personb  Person B      b@buddin.org      manual  repository
+`), out.String())
+
+	// Coauthors from the repository can be activated
+	err = cmd.TemplateSet("personb")
+	require.NoError(t, err)
+
+	// but only removed from the repository's manifest
+	err = cmd.ManifestRemove(ioutil.Discard, "personb")
+	require.EqualError(t, err, `coauthor "personb" is defined in the repository manifest and cannot be removed here`)
+
+	// Groups can include coauthors from the repository
+	err = cmd.ManifestGroupAdd("mob", "brett", "personb")
+	require.NoError(t, err)
+	err = cmd.ManifestGroupSet("pair", "personb")
+	require.NoError(t, err)
+	err = cmd.ManifestGroupAdd("mob", "nobody")
+	require.EqualError(t, err, `unknown coauthor "nobody"`)
+	layered, err := cmd.loadManifest()
+	require.NoError(t, err)
+	found, err := layered.Find("@mob", "@pair")
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+
+	// Writes never copy repository entries into the user's manifest
+	err = cmd.ManifestRemove(ioutil.Discard, "persona")
+	require.NoError(t, err)
+	m, err := manifest.Load(cmd.Paths.ManifestFile)
+	require.NoError(t, err)
+	require.Len(t, m.Coauthors, 1)
+}
+
+func TestManifestMigrate(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	legacy := []byte(`{"coauthors": {"brett": {"id": "brett", "type": "manual", "name": "Brett Buddin", "email": "brett@buddin.org"}}}`)
+	err := ioutil.WriteFile(cmd.Paths.ManifestFile, legacy, 0644)
+	require.NoError(t, err)
+
+	out := bytes.NewBuffer(nil)
+	err = cmd.ManifestMigrate(out, true)
+	require.Error(t, err)
+	require.Equal(t, fmt.Sprintf("%s: needs migration from version 1 to %d\n", cmd.Paths.ManifestFile, manifest.CurrentVersion), out.String())
+
+	out.Reset()
+	err = cmd.ManifestMigrate(out, false)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("%s: migrated from version 1 to %d\n", cmd.Paths.ManifestFile, manifest.CurrentVersion), out.String())
+
+	out.Reset()
+	err = cmd.ManifestMigrate(out, true)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("%s: up to date (version %d)\n", cmd.Paths.ManifestFile, manifest.CurrentVersion), out.String())
+}
+
+func TestManifestEdit(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	runGit(t, cmd.Paths.WorkDir, "checkout", "-q", "-b", "main")
+	err := cmd.ManifestAdd("brett", "Brett Budin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = cmd.ManifestAdd("persona", "Person A", "a@buddin.org")
+	require.NoError(t, err)
+	err = cmd.TemplateSet("brett", "persona")
+	require.NoError(t, err)
+	err = cmd.TemplateSetGlobal("brett")
+	require.NoError(t, err)
+	err = cmd.TemplateSetBranch("brett")
+	require.NoError(t, err)
+
+	err = cmd.ManifestEdit("brett", manifest.Edit{ID: "brettbuddin", Name: "Brett Buddin"})
+	require.NoError(t, err)
+
+	out := bytes.NewBuffer(nil)
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Equal(t, listExample(`
+ID           NAME          EMAIL             TYPE
+brettbuddin  Brett Buddin  brett@buddin.org  manual
+
+Scope: branch main
+
+Branch sets:
+  main  brettbuddin
+`), out.String())
+
+	// Templates are regenerated with the new details
+	repoPaths, err := cmd.Paths.Repository()
+	require.NoError(t, err)
+	for _, path := range []string{repoPaths.TemplateFile, cmd.Paths.GlobalTemplateFile} {
+		b, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		require.Contains(t, string(b), "# partner-id: brettbuddin\nCo-Authored-By: Brett Buddin <brett@buddin.org>\n")
+	}
+
+	err = cmd.ManifestEdit("brettbuddin", manifest.Edit{ID: "persona"})
+	require.EqualError(t, err, `coauthor with ID "persona" already exists`)
+}
+
+func TestManifestAliases(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	err := cmd.ManifestAdd("brettbuddin", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = cmd.ManifestAdd("persona", "Person A", "a@buddin.org")
+	require.NoError(t, err)
+
+	err = cmd.ManifestAlias("brettbuddin", "bb")
+	require.NoError(t, err)
+	err = cmd.ManifestAlias("persona", "bb")
+	require.EqualError(t, err, `alias "bb" is already used by coauthor "brettbuddin"`)
+
+	out := bytes.NewBuffer(nil)
+	err = cmd.ManifestList(out, false)
+	require.NoError(t, err)
+	require.Equal(t, listExample(`
+ID           NAME          EMAIL             TYPE    ALIASES
+brettbuddin  Brett Buddin  brett@buddin.org  manual  bb
+persona      Person A      a@buddin.org      manual  -
+`), out.String())
+
+	out.Reset()
+	err = cmd.ManifestListIDs(out)
+	require.NoError(t, err)
+	require.Equal(t, "bb\nbrettbuddin\npersona\n", out.String())
+
+	// Aliases are resolved to IDs when activated
+	err = cmd.TemplateSet("bb")
+	require.NoError(t, err)
+	out.Reset()
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Equal(t, listExample(`
+ID           NAME          EMAIL             TYPE
+brettbuddin  Brett Buddin  brett@buddin.org  manual
+
+Scope: repository
+`), out.String())
+
+	// Editing by alias follows the coauthor's ID into the active session
+	err = cmd.ManifestEdit("bb", manifest.Edit{ID: "brett"})
+	require.NoError(t, err)
+	out.Reset()
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "brett  Brett Buddin")
+
+	err = cmd.ManifestUnalias("bb")
+	require.NoError(t, err)
+	err = cmd.ManifestRemove(out, "bb")
+	require.EqualError(t, err, `unknown coauthor "bb"`)
+}
+
+func TestManifestAliases_RepositoryManifest(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	err := cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(cmd.Paths.WorkDir, ".partner.json"), []byte(`{
+  "coauthors": {
+    "persona": {"id": "persona", "type": "manual", "name": "Person A", "email": "a@buddin.org", "aliases": ["pa"]}
+  }
+}`), 0644)
+	require.NoError(t, err)
+
+	// Aliases can't clash with coauthors from the repository
+	err = cmd.ManifestAlias("brett", "persona")
+	require.EqualError(t, err, `alias "persona" of coauthor "brett" in the user manifest is already the ID of coauthor "persona" in the repository manifest`)
+	err = cmd.ManifestAlias("brett", "pa")
+	require.EqualError(t, err, `alias "pa" is used by both coauthor "persona" in the repository manifest and coauthor "brett" in the user manifest`)
+	err = cmd.ManifestAlias("brett", "bb")
+	require.NoError(t, err)
+
+	// A conflict pushed to the repository doesn't get in the way of reading
+	// the manifest, where the repository's alias takes precedence
+	err = ioutil.WriteFile(filepath.Join(cmd.Paths.WorkDir, ".partner.json"), []byte(`{
+  "coauthors": {
+    "persona": {"id": "persona", "type": "manual", "name": "Person A", "email": "a@buddin.org", "aliases": ["bb"]}
+  }
+}`), 0644)
+	require.NoError(t, err)
+	err = cmd.TemplateSet("bb")
+	require.NoError(t, err)
+	set, err := cmd.activeSet()
+	require.NoError(t, err)
+	require.Equal(t, []string{"persona"}, set.IDs)
+
+	// but the user's manifest isn't written until the conflict is fixed
+	err = cmd.ManifestAdd("personb", "Person B", "b@buddin.org")
+	require.EqualError(t, err, `alias "bb" is used by both coauthor "persona" in the repository manifest and coauthor "brett" in the user manifest`)
+	err = cmd.ManifestUnalias("bb")
+	require.NoError(t, err)
+	err = cmd.ManifestAdd("personb", "Person B", "b@buddin.org")
+	require.NoError(t, err)
+}
+
+func TestManifestFetchAdd_Hosts(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	public := manifest.Coauthor{
+		ID:    "brettbuddin",
+		Name:  "Brett Buddin",
+		Email: "6059+brettbuddin@users.noreply.github.com",
+		Type:  manifest.CoauthorTypeGitHub,
+		Host:  "github.com",
+	}
+	err := cmd.ManifestFetchAdd(fetcher{coauthor: public}, "brettbuddin")
+	require.NoError(t, err)
+	err = cmd.ManifestFetchAdd(fetcher{coauthor: public}, "brettbuddin")
+	require.EqualError(t, err, `coauthor with ID "brettbuddin" already exists`)
+
+	// The same username on another instance is someone else
+	enterprise := public
+	enterprise.Email = "12+brettbuddin@users.noreply.github.example.com"
+	enterprise.Host = "github.example.com"
+	err = cmd.ManifestFetchAdd(fetcher{coauthor: enterprise}, "brettbuddin")
+	require.NoError(t, err)
+
+	m, err := manifest.Load(cmd.Paths.ManifestFile)
+	require.NoError(t, err)
+	ca, ok := m.Lookup("github.example.com/brettbuddin")
+	require.True(t, ok)
+	require.Equal(t, enterprise.Email, ca.Email)
+}
+
+func TestManifestFetchAdd_Aliases(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	err := cmd.ManifestAdd("brettbuddin", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+
+	// Suggested aliases are only added while they're free
+	err = cmd.ManifestFetchAdd(fetcher{coauthor: manifest.Coauthor{
+		ID:      "557058:5a3c1e2f-8b7d-4c6a-9e1f-0d2b3c4a5e6f",
+		Name:    "Brett Buddin",
+		Email:   "brett@buddin.org",
+		Type:    manifest.CoauthorTypeBitbucket,
+		Host:    "bitbucket.org",
+		Aliases: []string{"brettbuddin", "bb"},
+	}}, "557058:5a3c1e2f-8b7d-4c6a-9e1f-0d2b3c4a5e6f")
+	require.NoError(t, err)
+
+	m, err := manifest.Load(cmd.Paths.ManifestFile)
+	require.NoError(t, err)
+	ca, ok := m.Lookup("bb")
+	require.True(t, ok)
+	require.Equal(t, []string{"bb"}, ca.Aliases)
+	ca, ok = m.Lookup("brettbuddin")
+	require.True(t, ok)
+	require.Equal(t, manifest.CoauthorTypeManual, ca.Type)
+}
@@ -0,0 +1,155 @@
+package command
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+)
+
+// refresh is a change to a coauthor found by ManifestRefresh
+type refresh struct {
+	old     manifest.Coauthor
+	updated manifest.Coauthor
+}
+
+func (r refresh) edit() manifest.Edit {
+	var ed manifest.Edit
+	if r.updated.ID != r.old.ID {
+		ed.ID = r.updated.ID
+	}
+	if r.updated.Name != "" && r.updated.Name != r.old.Name {
+		ed.Name = r.updated.Name
+	}
+	if r.updated.Email != r.old.Email {
+		ed.Email = r.updated.Email
+	}
+	return ed
+}
+
+// ManifestRefresh fetches coauthors in the user's manifest again from the
+// service they were added from, and applies any changes to their ID, name or
+// email address. Without any IDs, every coauthor whose type has a refresher is
+// refreshed. The changes are listed on w and only applied once confirmed on
+// in, unless yes is set.
+//
+// Coauthors who no longer exist are reported and left alone.
+func (c *Command) ManifestRefresh(in io.Reader, w io.Writer, yes bool, refreshers map[string]UserRefresher, ids ...string) error {
+	m, err := manifest.Load(c.Paths.ManifestFile)
+	if err != nil {
+		return err
+	}
+
+	var selected []manifest.Coauthor
+	if len(ids) == 0 {
+		for _, ca := range m.Slice() {
+			if _, ok := refreshers[ca.Type]; ok {
+				selected = append(selected, ca)
+			}
+		}
+		sort.Slice(selected, func(i, j int) bool {
+			return strings.ToLower(selected[i].ID) < strings.ToLower(selected[j].ID)
+		})
+	} else {
+		for _, id := range ids {
+			ca, ok := m.Lookup(id)
+			if !ok {
+				return fmt.Errorf("unknown coauthor %q", id)
+			}
+			if _, ok := refreshers[ca.Type]; !ok {
+				return fmt.Errorf("coauthor %q was added as %s and cannot be refreshed", ca.ID, ca.Type)
+			}
+			selected = append(selected, ca)
+		}
+	}
+
+	var (
+		changes []refresh
+		failed  int
+	)
+	for _, ca := range selected {
+		updated, err := refreshers[ca.Type].Refresh(ca)
+		var notFound UserNotFoundError
+		switch {
+		case errors.As(err, &notFound):
+			fmt.Fprintf(w, "%s: no longer exists on %s, left unchanged\n", ca.ID, notFound.Service)
+			continue
+		case err != nil:
+			fmt.Fprintf(w, "%s: %s\n", ca.ID, err)
+			failed++
+			continue
+		}
+		// Fetchers return the bare username, so keep the ID qualified with
+		// the host when it was added that way.
+		if login := coauthorLogin(ca); login != ca.ID {
+			updated.ID = strings.TrimSuffix(ca.ID, login) + updated.ID
+		}
+		r := refresh{old: ca, updated: updated}
+		if r.edit() != (manifest.Edit{}) {
+			changes = append(changes, r)
+		}
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "All coauthors are up to date")
+	} else {
+		writeRefreshes(w, changes)
+		apply := yes
+		if !apply {
+			fmt.Fprint(w, "Apply these changes? [y/N] ")
+			answer, _ := bufio.NewReader(in).ReadString('\n')
+			answer = strings.ToLower(strings.TrimSpace(answer))
+			apply = answer == "y" || answer == "yes"
+		}
+		if !apply {
+			fmt.Fprintln(w, "No changes applied")
+		} else if err := c.applyRefreshes(changes); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to refresh %d coauthor(s)", failed)
+	}
+	return nil
+}
+
+func (c *Command) applyRefreshes(changes []refresh) error {
+	err := c.updateManifest(func(m *manifest.Manifest) error {
+		for _, r := range changes {
+			if _, err := m.Edit(r.old.ID, r.edit()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, r := range changes {
+		if err := c.followEdit(r.old.ID, r.updated.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeRefreshes(w io.Writer, changes []refresh) {
+	for _, r := range changes {
+		ed := r.edit()
+		fmt.Fprintln(w, r.old.ID)
+		if ed.ID != "" {
+			fmt.Fprintf(w, "  - id:    %s\n  + id:    %s\n", r.old.ID, ed.ID)
+		}
+		if ed.Name != "" {
+			fmt.Fprintf(w, "  - name:  %s\n  + name:  %s\n", r.old.Name, ed.Name)
+		}
+		if ed.Email != "" {
+			fmt.Fprintf(w, "  - email: %s\n  + email: %s\n", r.old.Email, ed.Email)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/stretchr/testify/require"
+)
+
+type refresher map[string]manifest.Coauthor
+
+func (r refresher) Refresh(ca manifest.Coauthor) (manifest.Coauthor, error) {
+	updated, ok := r[ca.ID]
+	if !ok {
+		return manifest.Coauthor{}, UserNotFoundError{Username: ca.ID, Service: "GitHub", Message: "Not Found"}
+	}
+	if updated.ID == "" {
+		return manifest.Coauthor{}, fmt.Errorf("rate limited")
+	}
+	return updated, nil
+}
+
+func TestManifestRefresh(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	err := cmd.ManifestFetchAdd(sequenceFetcher{
+		{ID: "brett", Name: "Brett", Email: "6059+brett@users.noreply.github.com", Type: manifest.CoauthorTypeGitHub},
+		{ID: "gone", Name: "Gone", Email: "1+gone@users.noreply.github.com", Type: manifest.CoauthorTypeGitHub},
+	}, "brett", "gone")
+	require.NoError(t, err)
+	err = cmd.ManifestAdd("persona", "Person A", "a@buddin.org")
+	require.NoError(t, err)
+	err = cmd.TemplateSet("brett")
+	require.NoError(t, err)
+
+	refreshers := map[string]UserRefresher{
+		manifest.CoauthorTypeGitHub: refresher{
+			"brett": {ID: "brettbuddin", Name: "Brett Buddin", Email: "6059+brettbuddin@users.noreply.github.com", Type: manifest.CoauthorTypeGitHub},
+		},
+	}
+
+	// Declining leaves everything as it was
+	out := bytes.NewBuffer(nil)
+	err = cmd.ManifestRefresh(strings.NewReader("n\n"), out, false, refreshers)
+	require.NoError(t, err)
+	require.Equal(t, `gone: no longer exists on GitHub, left unchanged
+brett
+  - id:    brett
+  + id:    brettbuddin
+  - name:  Brett
+  + name:  Brett Buddin
+  - email: 6059+brett@users.noreply.github.com
+  + email: 6059+brettbuddin@users.noreply.github.com
+Apply these changes? [y/N] No changes applied
+`, out.String())
+	m, err := manifest.Load(cmd.Paths.ManifestFile)
+	require.NoError(t, err)
+	require.Contains(t, m.Coauthors, "brett")
+
+	out.Reset()
+	err = cmd.ManifestRefresh(strings.NewReader("y\n"), out, false, refreshers)
+	require.NoError(t, err)
+
+	// The active session follows the rename
+	out.Reset()
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Equal(t, listExample(`
+ID           NAME          EMAIL                                      TYPE
+brettbuddin  Brett Buddin  6059+brettbuddin@users.noreply.github.com  github
+
+Scope: repository
+`), out.String())
+
+	out.Reset()
+	err = cmd.ManifestRefresh(nil, out, true, refreshers, "brettbuddin")
+	require.NoError(t, err)
+	require.Equal(t, "brettbuddin: no longer exists on GitHub, left unchanged\nAll coauthors are up to date\n", out.String())
+
+	// Manually added coauthors can't be refreshed
+	err = cmd.ManifestRefresh(nil, out, true, refreshers, "persona")
+	require.EqualError(t, err, `coauthor "persona" was added as manual and cannot be refreshed`)
+}
+
+func TestManifestRefresh_Failure(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	err := cmd.ManifestFetchAdd(sequenceFetcher{
+		{ID: "brett", Name: "Brett", Email: "6059+brett@users.noreply.github.com", Type: manifest.CoauthorTypeGitHub},
+	}, "brett")
+	require.NoError(t, err)
+
+	refreshers := map[string]UserRefresher{
+		manifest.CoauthorTypeGitHub: refresher{"brett": {}},
+	}
+	out := bytes.NewBuffer(nil)
+	err = cmd.ManifestRefresh(nil, out, true, refreshers)
+	require.EqualError(t, err, "failed to refresh 1 coauthor(s)")
+	require.Equal(t, "brett: rate limited\nAll coauthors are up to date\n", out.String())
+}
+
+func TestManifestRefresh_QualifiedIDs(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	err := cmd.ManifestFetchAdd(sequenceFetcher{
+		{ID: "brettbuddin", Name: "Brett", Email: "6059+brettbuddin@users.noreply.github.com", Type: manifest.CoauthorTypeGitHub, Host: "github.com"},
+	}, "brettbuddin")
+	require.NoError(t, err)
+	err = cmd.ManifestFetchAdd(sequenceFetcher{
+		{ID: "brettbuddin", Name: "Brett", Email: "12+brettbuddin@users.noreply.github.example.com", Type: manifest.CoauthorTypeGitHub, Host: "github.example.com"},
+	}, "brettbuddin")
+	require.NoError(t, err)
+
+	// Fetchers return the bare username for both
+	refreshers := map[string]UserRefresher{
+		manifest.CoauthorTypeGitHub: refresher{
+			"brettbuddin":                    {ID: "brettbuddin", Name: "Brett Buddin", Email: "6059+brettbuddin@users.noreply.github.com", Type: manifest.CoauthorTypeGitHub, Host: "github.com"},
+			"github.example.com/brettbuddin": {ID: "brettbuddin", Name: "Brett Buddin", Email: "12+brettbuddin@users.noreply.github.example.com", Type: manifest.CoauthorTypeGitHub, Host: "github.example.com"},
+		},
+	}
+	out := bytes.NewBuffer(nil)
+	err = cmd.ManifestRefresh(nil, out, true, refreshers)
+	require.NoError(t, err)
+	require.NotContains(t, out.String(), "id:")
+
+	m, err := manifest.Load(cmd.Paths.ManifestFile)
+	require.NoError(t, err)
+	require.Equal(t, "Brett Buddin", m.Coauthors["brettbuddin"].Name)
+	require.Equal(t, "Brett Buddin", m.Coauthors["github.example.com/brettbuddin"].Name)
+
+	// A renamed account keeps the qualification
+	refreshers[manifest.CoauthorTypeGitHub].(refresher)["github.example.com/brettbuddin"] = manifest.Coauthor{ID: "bbuddin", Name: "Brett Buddin", Email: "12+bbuddin@users.noreply.github.example.com", Type: manifest.CoauthorTypeGitHub, Host: "github.example.com"}
+	out.Reset()
+	err = cmd.ManifestRefresh(nil, out, true, refreshers, "github.example.com/brettbuddin")
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "  + id:    github.example.com/bbuddin\n")
+}
+
+// sequenceFetcher fetches the coauthor with the requested ID
+type sequenceFetcher []manifest.Coauthor
+
+func (f sequenceFetcher) Fetch(username string) (manifest.Coauthor, error) {
+	for _, ca := range f {
+		if ca.ID == username {
+			return ca, nil
+		}
+	}
+	return manifest.Coauthor{}, fmt.Errorf("unknown user %q", username)
+}
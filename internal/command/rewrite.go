@@ -0,0 +1,133 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/brettbuddin/partner/internal/repository"
+	"github.com/brettbuddin/partner/internal/trailer"
+)
+
+// Rewrite adds coauthors to every commit in a <base>..<head> range, where
+// head is a branch or HEAD. The active coauthors are used unless IDs are
+// given. Commits that already credit the coauthors keep their message. Only
+// commit messages change, so rewriting can't conflict; ranges containing
+// merges are refused, as are ranges that have already been pushed unless
+// force is set. With dryRun, the commits that would change are listed and
+// nothing is rewritten.
+func (c *Command) Rewrite(w io.Writer, revRange string, dryRun, force bool, ids ...string) error {
+	repoPaths, err := c.Paths.Repository()
+	if err != nil {
+		return err
+	}
+	coauthors, err := c.coauthorsOrActive(ids...)
+	if err != nil {
+		return err
+	}
+
+	parts := strings.Split(revRange, "..")
+	if len(parts) != 2 || strings.HasPrefix(parts[1], ".") || parts[0] == "" {
+		return fmt.Errorf("invalid range %q: expected <base>..<head>", revRange)
+	}
+	base, head := parts[0], parts[1]
+	if head == "" {
+		head = "HEAD"
+	}
+
+	ref, err := repository.FullRefName(repoPaths.Root, head)
+	if err != nil {
+		return err
+	}
+	if ref != "HEAD" && !strings.HasPrefix(ref, "refs/heads/") {
+		return fmt.Errorf("%s is not a branch", head)
+	}
+
+	merges, err := repository.RevList(repoPaths.Root, "--min-parents=2", base+".."+head)
+	if err != nil {
+		return err
+	}
+	if len(merges) > 0 {
+		return fmt.Errorf("refusing to rewrite a range containing merge commits (%s)", merges[0])
+	}
+	commits, err := repository.RevList(repoPaths.Root, base+".."+head)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		return nil
+	}
+
+	if !force {
+		remotes, err := repository.RemoteRefsContaining(repoPaths.Root, commits[0])
+		if err != nil {
+			return err
+		}
+		if len(remotes) > 0 {
+			return fmt.Errorf("%.7s has already been pushed to %s (use --force to rewrite anyway)", commits[0], strings.Join(remotes, ", "))
+		}
+	}
+
+	var (
+		rewritten = map[string]string{}
+		oldHead   = commits[len(commits)-1]
+		newHead   = oldHead
+	)
+	for _, hash := range commits {
+		commit, err := repository.ReadCommit(repoPaths.Root, hash)
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		for i, p := range commit.Parents {
+			if np, ok := rewritten[p]; ok {
+				commit.Parents[i] = np
+				changed = true
+			}
+		}
+		missing, err := trailer.Uncredited(repoPaths.Root, []byte(commit.Message), coauthors)
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			fmt.Fprintf(w, "%.7s %s (+ %s)\n", hash, subject(commit.Message), coauthorIDs(missing))
+			changed = true
+			if !dryRun {
+				msg, err := trailer.AppendCoauthors(repoPaths.Root, []byte(commit.Message), missing)
+				if err != nil {
+					return err
+				}
+				commit.Message = string(msg)
+			}
+		}
+		if !changed || dryRun {
+			continue
+		}
+
+		newHash, err := repository.WriteCommit(repoPaths.Root, commit)
+		if err != nil {
+			return err
+		}
+		rewritten[hash] = newHash
+		newHead = newHash
+	}
+
+	if dryRun || newHead == oldHead {
+		return nil
+	}
+	return repository.UpdateRef(repoPaths.Root, ref, newHead, oldHead, "partner: rewrite coauthors")
+}
+
+func subject(message string) string {
+	return strings.SplitN(strings.TrimSpace(message), "\n", 2)[0]
+}
+
+func coauthorIDs(coauthors []manifest.Coauthor) string {
+	var ids []string
+	for _, ca := range coauthors {
+		ids = append(ids, ca.ID)
+	}
+	return strings.Join(ids, ", ")
+}
@@ -0,0 +1,90 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewrite(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	dir := cmd.Paths.WorkDir
+
+	commitAs(t, dir, "Base")
+	base := runGit(t, dir, "rev-parse", "HEAD")
+	commitAs(t, dir, "First")
+	commitAs(t, dir, "Second\n\nCo-authored-by: Person A <a@buddin.org>")
+	commitAs(t, dir, "Third")
+	before := runGit(t, dir, "log", "--format=%H", base+"..HEAD")
+
+	err := cmd.ManifestAdd("persona", "Person A", "a@buddin.org")
+	require.NoError(t, err)
+
+	// A dry run lists the commits that would change and leaves them alone
+	out := bytes.NewBuffer(nil)
+	err = cmd.Rewrite(out, base+"..HEAD", true, false, "persona")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	require.True(t, strings.HasSuffix(lines[0], " First (+ persona)"), lines[0])
+	require.True(t, strings.HasSuffix(lines[1], " Third (+ persona)"), lines[1])
+	require.Equal(t, before, runGit(t, dir, "log", "--format=%H", base+"..HEAD"))
+
+	out.Truncate(0)
+	err = cmd.Rewrite(out, base+"..HEAD", false, false, "persona")
+	require.NoError(t, err)
+
+	require.Equal(t, fmt.Sprintf(`Third
+
+Co-Authored-By: Person A <a@buddin.org>
+%[1]sSecond
+
+Co-authored-by: Person A <a@buddin.org>
+%[1]sFirst
+
+Co-Authored-By: Person A <a@buddin.org>
+%[1]s`, "--\n"), runGit(t, dir, "log", "--format=%B--", base+"..HEAD")+"\n")
+	require.Equal(t, base, runGit(t, dir, "rev-parse", "HEAD~3"))
+
+	// Running again changes nothing
+	after := runGit(t, dir, "rev-parse", "HEAD")
+	err = cmd.Rewrite(out, base+"..HEAD", false, false, "persona")
+	require.NoError(t, err)
+	require.Equal(t, after, runGit(t, dir, "rev-parse", "HEAD"))
+}
+
+func TestRewrite_Refusals(t *testing.T) {
+	cmd := New(newWorkspace(t))
+	dir := cmd.Paths.WorkDir
+
+	err := cmd.ManifestAdd("persona", "Person A", "a@buddin.org")
+	require.NoError(t, err)
+
+	commitAs(t, dir, "Base")
+	base := runGit(t, dir, "rev-parse", "HEAD")
+	runGit(t, dir, "checkout", "-q", "-b", "side")
+	commitAs(t, dir, "Side")
+	runGit(t, dir, "checkout", "-q", "-")
+	commitAs(t, dir, "Main")
+	runGit(t, dir, "merge", "--no-ff", "-q", "-m", "Merge side", "side")
+
+	out := bytes.NewBuffer(nil)
+	err = cmd.Rewrite(out, base+"..HEAD", false, false, "persona")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "merge commits")
+
+	err = cmd.Rewrite(out, base, false, false, "persona")
+	require.Error(t, err)
+
+	err = cmd.Rewrite(out, base+".."+base, false, false, "persona")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not a branch")
+
+	runGit(t, dir, "update-ref", "refs/remotes/origin/side", "side")
+	err = cmd.Rewrite(out, base+"..side", false, false, "persona")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "origin/side")
+}
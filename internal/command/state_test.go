@@ -0,0 +1,78 @@
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brettbuddin/partner/internal/state"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState(t *testing.T) {
+	cmd := New(newWorkspace(t))
+
+	err := cmd.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = cmd.TemplateSet("brett")
+	require.NoError(t, err)
+
+	repoPaths, err := cmd.Paths.Repository()
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(repoPaths.CommonDir, "partner/state.json"), repoPaths.StateFile)
+
+	s, err := state.Load(repoPaths.StateFile)
+	require.NoError(t, err)
+	require.Equal(t, []string{"brett"}, s.IDs)
+	require.Equal(t, "local", s.Scope)
+	require.Equal(t, Version(), s.Version)
+	require.False(t, s.ActivatedAt.IsZero())
+	require.Nil(t, s.ExpiresAt)
+
+	// Edits to the template don't change who is active
+	err = ioutil.WriteFile(repoPaths.TemplateFile, []byte("edited\n"), 0644)
+	require.NoError(t, err)
+	out := bytes.NewBuffer(nil)
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "brett")
+
+	err = cmd.TemplateClear()
+	require.NoError(t, err)
+	_, err = os.Stat(repoPaths.StateFile)
+	require.True(t, os.IsNotExist(err), "state was not removed")
+}
+
+func TestState_MigrateTemplateSession(t *testing.T) {
+	cmd := New(newWorkspace(t))
+
+	err := cmd.ManifestAdd("persona", "Person A", "a@buddin.org")
+	require.NoError(t, err)
+	err = cmd.ManifestAdd("personb", "Person B", "b@buddin.org")
+	require.NoError(t, err)
+
+	// A session started by an older version of partner
+	repoPaths, err := cmd.Paths.Repository()
+	require.NoError(t, err)
+	legacy, err := ioutil.ReadFile("../template/testdata/gitmessage.txt")
+	require.NoError(t, err)
+	err = ioutil.WriteFile(repoPaths.TemplateFile, legacy, 0644)
+	require.NoError(t, err)
+
+	out := bytes.NewBuffer(nil)
+	err = cmd.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Equal(t, listExample(`
+ID       NAME      EMAIL         TYPE
+persona  Person A  a@buddin.org  manual
+personb  Person B  b@buddin.org  manual
+
+Scope: repository
+`), out.String())
+
+	s, err := state.Load(repoPaths.StateFile)
+	require.NoError(t, err)
+	require.Equal(t, []string{"persona", "personb"}, s.IDs)
+}
@@ -4,76 +4,229 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/brettbuddin/partner/internal/lockedfile"
 	"github.com/brettbuddin/partner/internal/manifest"
 	"github.com/brettbuddin/partner/internal/repository"
+	"github.com/brettbuddin/partner/internal/state"
 	"github.com/brettbuddin/partner/internal/template"
 )
 
-// TemplateStatus lists active coauthors
+// TemplateStatus lists active coauthors and the scope they were activated
+// in, followed by the coauthors linked to each branch
 func (c *Command) TemplateStatus(w io.Writer) error {
-	m, err := manifest.Load(c.Paths.ManifestFile)
+	m, err := c.loadManifest()
 	if err != nil {
 		return err
 	}
 
-	repoPaths, err := c.Paths.Repository()
+	set, err := c.activeSet()
 	if err != nil {
 		return err
 	}
-
-	ids, err := template.ExtractIDs(repoPaths.TemplateFile)
+	active, err := m.Find(set.IDs...)
 	if err != nil {
 		return err
 	}
-	active, err := m.Find(ids...)
+	if active, err = selectIdentities(c.Paths.WorkDir, active); err != nil {
+		return err
+	}
+	if len(active) > 0 {
+		if err := writeList(w, active...); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\nScope: %s\n", set.Source)
+		if !set.Expires.IsZero() {
+			fmt.Fprintf(w, "Expires: in %s (%s)\n", formatRemaining(set.Expires.Sub(now())), set.Expires.Local().Format(time.RFC1123))
+		}
+	} else if set.Excluded {
+		fmt.Fprintf(w, "Coauthors are never added on branch %s\n", set.Branch)
+	}
+
+	repoPaths, err := c.Paths.Repository()
 	if err != nil {
+		return nil
+	}
+	sets, err := branchSets(repoPaths.Root)
+	if err != nil || len(sets) == 0 {
 		return err
 	}
-	return writeList(w, active...)
+	fmt.Fprintln(w, "\nBranch sets:")
+	tabw := tabwriter.NewWriter(w, 5, 2, 2, ' ', 0)
+	for _, branch := range sortedBranches(sets) {
+		fmt.Fprintf(tabw, "  %s\t%s\n", branch, strings.Join(sets[branch], ", "))
+	}
+	return tabw.Flush()
 }
 
 // TemplateSet activates a coauthor in the Template
 func (c *Command) TemplateSet(ids ...string) error {
-	m, err := manifest.Load(c.Paths.ManifestFile)
+	return c.TemplateSetFor(ioutil.Discard, 0, ids...)
+}
+
+// TemplateSetFor activates a coauthor in the Template for a limited time. A
+// zero ttl uses the partner.ttl git configuration, if any. Only the
+// prepare-commit-msg hook can keep trailers that are already in the template
+// off commits once they expire, so it is installed before the session starts,
+// which is reported on w.
+func (c *Command) TemplateSetFor(w io.Writer, ttl time.Duration, ids ...string) error {
+	repoPaths, err := c.Paths.Repository()
+	if err != nil {
+		return err
+	}
+	if ttl == 0 {
+		if ttl, err = defaultTTL(repoPaths.Dir); err != nil {
+			return err
+		}
+	}
+	if ttl > 0 {
+		m, err := c.loadManifest()
+		if err != nil {
+			return err
+		}
+		if _, err := m.Find(ids...); err != nil {
+			return err
+		}
+		installed, err := c.autoInstallHook()
+		if err != nil {
+			return fmt.Errorf("failed to install the %s hook, which expiring coauthors rely on: %w", HookPrepareCommitMsg, err)
+		}
+		if installed {
+			fmt.Fprintf(w, "Installed the %s hook to stop crediting the coauthors once they expire\n", HookPrepareCommitMsg)
+		}
+	}
+	return c.templateSet(repoPaths.TemplatePaths, ttl, ids...)
+}
+
+// TemplateSetGlobal activates a coauthor in the Template used by every
+// repository. Global sessions never expire: without a hook to remove them,
+// trailers in the global template would outlive their session.
+func (c *Command) TemplateSetGlobal(ids ...string) error {
+	return c.templateSet(c.Paths.Global(), 0, ids...)
+}
+
+func (c *Command) templateSet(tmplPaths TemplatePaths, ttl time.Duration, ids ...string) error {
+	m, err := c.loadManifest()
 	if err != nil {
 		return err
 	}
 
-	repoPaths, err := c.Paths.Repository()
+	unlock, err := lockedfile.Lock(tmplPaths.StateFile)
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
-	existingIDs, err := template.ExtractIDs(repoPaths.TemplateFile)
+	existing, err := loadSession(tmplPaths)
 	if err != nil {
 		return err
 	}
-	ids = uniqueStrings(append(ids, existingIDs...))
+	if existing != nil {
+		ids = uniqueStrings(append(ids, existing.IDs...))
+	}
 
 	coauthors, err := m.Find(ids...)
 	if err != nil {
 		return err
 	}
 
-	t := template.Template{Coauthors: coauthors}
-	if err := template.WriteFile(repoPaths.TemplateFile, t); err != nil {
+	s := &state.State{
+		Version:     Version(),
+		Scope:       string(tmplPaths.Scope),
+		ActivatedAt: now().UTC(),
+	}
+	for _, ca := range coauthors {
+		s.IDs = append(s.IDs, ca.ID)
+	}
+	if ttl > 0 {
+		expires := s.ActivatedAt.Add(ttl)
+		s.ExpiresAt = &expires
+	}
+	if err := state.WriteFile(tmplPaths.StateFile, s); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+
+	if err := c.writeTemplate(tmplPaths, s, coauthors); err != nil {
 		return err
 	}
-	return repository.SetCommitTemplate(repoPaths.Root, repoPaths.TemplateFile)
+	return repository.SetCommitTemplate(tmplPaths.Dir, tmplPaths.Scope, tmplPaths.TemplateFile)
 }
 
-// TemplateClear emptys the coauthors Template
+// writeTemplate generates the commit template for a state. The global
+// template is shared by every repository, so it credits each coauthor's
+// default email address, and the hook picks identities where it's installed.
+func (c *Command) writeTemplate(tmplPaths TemplatePaths, s *state.State, coauthors []manifest.Coauthor) error {
+	base, err := c.baseTemplate(tmplPaths)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(tmplPaths.TemplateFile), os.ModePerm); err != nil {
+		return err
+	}
+	if tmplPaths.Scope != repository.ScopeGlobal {
+		coauthors, err = selectIdentities(tmplPaths.Dir, coauthors)
+		if err != nil {
+			return err
+		}
+	}
+	t := template.Template{Base: base, Coauthors: coauthors, Expires: expiry(s)}
+	return template.WriteFile(tmplPaths.TemplateFile, t)
+}
+
+// TemplateClear emptys the coauthors Template and removes the git hooks
+// partner installed on its own, unless branch sets or exclusions still rely on
+// them. Hooks installed with HookInstall are left in place.
 func (c *Command) TemplateClear() error {
 	repoPaths, err := c.Paths.Repository()
 	if err != nil {
 		return err
 	}
+	auto, err := hookAutoInstalled(repoPaths.Root)
+	if err != nil {
+		return err
+	}
+	needed, err := hookNeeded(repoPaths.Root)
+	if err != nil {
+		return err
+	}
+	if auto && !needed {
+		if err := c.HookUninstall(); err != nil {
+			return err
+		}
+	}
+	return templateClear(repoPaths.TemplatePaths)
+}
+
+// TemplateClearGlobal emptys the coauthors Template used by every repository
+func (c *Command) TemplateClearGlobal() error {
+	return templateClear(c.Paths.Global())
+}
+
+func templateClear(tmplPaths TemplatePaths) error {
+	unlock, err := lockedfile.Lock(tmplPaths.StateFile)
+	if err != nil {
+		repository.UnsetCommitTemplate(tmplPaths.Dir, tmplPaths.Scope)
+		return err
+	}
+	defer unlock()
+	return clearSession(tmplPaths)
+}
 
-	defer repository.UnsetCommitTemplate(repoPaths.Root)
+// clearSession removes the state and commit template of a scope. The caller
+// holds the lock on the state file.
+func clearSession(tmplPaths TemplatePaths) error {
+	defer repository.UnsetCommitTemplate(tmplPaths.Dir, tmplPaths.Scope)
 
-	if err := os.Remove(repoPaths.TemplateFile); err != nil {
+	if err := state.Remove(tmplPaths.StateFile); err != nil {
+		return fmt.Errorf("failed to remove state: %w", err)
+	}
+	if err := os.Remove(tmplPaths.TemplateFile); err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil
 		}
@@ -82,6 +235,180 @@ func (c *Command) TemplateClear() error {
 	return nil
 }
 
+// activeSet describes the coauthors in effect and where they were activated
+type activeSet struct {
+	IDs []string
+	// Source is a description of the scope the coauthors were activated in.
+	Source string
+	// Expires is when the coauthors stop being credited. The zero time means
+	// never.
+	Expires time.Time
+	// Branch is the branch checked out, if any.
+	Branch string
+	// Excluded reports whether coauthors are never added on Branch.
+	Excluded bool
+}
+
+// activeSet returns the coauthors in effect. Coauthors linked to the current
+// branch take precedence over those activated for the repository, which in
+// turn take precedence over those activated globally.
+func (c *Command) activeSet() (activeSet, error) {
+	// Outside of a repository only the global scope applies.
+	if repoPaths, err := c.Paths.Repository(); err == nil {
+		branch, err := repository.CurrentBranch(repoPaths.Root)
+		if err != nil {
+			return activeSet{}, err
+		}
+		excluded, err := branchExcluded(repoPaths.Root, branch)
+		if err != nil {
+			return activeSet{}, err
+		}
+		if excluded {
+			return activeSet{Branch: branch, Excluded: true}, nil
+		}
+
+		sets, err := branchSets(repoPaths.Root)
+		if err != nil {
+			return activeSet{}, err
+		}
+		if ids := sets[branch]; len(ids) > 0 {
+			return activeSet{IDs: ids, Source: "branch " + branch, Branch: branch}, nil
+		}
+
+		ids, expires, err := session(repoPaths.TemplatePaths)
+		if err != nil {
+			return activeSet{}, err
+		}
+		if len(ids) > 0 {
+			return activeSet{IDs: ids, Source: scopeName(repoPaths.Scope), Expires: expires, Branch: branch}, nil
+		}
+	}
+
+	ids, expires, err := session(c.Paths.Global())
+	if err != nil {
+		return activeSet{}, err
+	}
+	return activeSet{IDs: ids, Source: scopeName(repository.ScopeGlobal), Expires: expires}, nil
+}
+
+// session returns the IDs of the coauthors activated in a scope and when they
+// expire. A session that has expired is cleared.
+func session(tmplPaths TemplatePaths) ([]string, time.Time, error) {
+	s, err := loadState(tmplPaths)
+	if err != nil || s == nil {
+		return nil, time.Time{}, err
+	}
+	if s.Expired(now()) {
+		if err := templateClear(tmplPaths); err != nil {
+			return nil, time.Time{}, err
+		}
+		return nil, time.Time{}, nil
+	}
+	return s.IDs, expiry(s), nil
+}
+
+// loadSession reads the state of a scope like loadState, except that a session
+// that has expired is cleared and nil is returned. The caller holds the lock
+// on the state file.
+func loadSession(tmplPaths TemplatePaths) (*state.State, error) {
+	s, err := loadState(tmplPaths)
+	if err != nil || s == nil || !s.Expired(now()) {
+		return s, err
+	}
+	return nil, clearSession(tmplPaths)
+}
+
+// loadState reads the state of a scope. Sessions started before partner kept
+// state are recovered from the commit template and migrated. A nil state is
+// returned if no coauthors are active.
+func loadState(tmplPaths TemplatePaths) (*state.State, error) {
+	s, err := state.Load(tmplPaths.StateFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	if s != nil {
+		return s, nil
+	}
+
+	ids, err := template.ExtractIDs(tmplPaths.TemplateFile)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	info, err := os.Stat(tmplPaths.TemplateFile)
+	if err != nil {
+		return nil, err
+	}
+	expires, err := template.ExtractExpiry(tmplPaths.TemplateFile)
+	if err != nil {
+		return nil, err
+	}
+	s = &state.State{
+		Version:     Version(),
+		Scope:       string(tmplPaths.Scope),
+		IDs:         ids,
+		ActivatedAt: info.ModTime().UTC(),
+	}
+	if !expires.IsZero() {
+		s.ExpiresAt = &expires
+	}
+	if err := state.WriteFile(tmplPaths.StateFile, s); err != nil {
+		return nil, fmt.Errorf("failed to migrate state: %w", err)
+	}
+	return s, nil
+}
+
+func expiry(s *state.State) time.Time {
+	if s.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return *s.ExpiresAt
+}
+
+// defaultTTL returns how long coauthors stay active according to the
+// partner.ttl git configuration. Zero means forever.
+func defaultTTL(dir string) (time.Duration, error) {
+	v, ok, err := repository.Config(dir, "partner.ttl")
+	if err != nil || !ok {
+		return 0, err
+	}
+	ttl, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid partner.ttl %q: %w", v, err)
+	}
+	return ttl, nil
+}
+
+// baseTemplate reads the commit template that was configured before partner's
+// own, so that the coauthors can be appended to it.
+func (c *Command) baseTemplate(tmplPaths TemplatePaths) (string, error) {
+	path, err := repository.BaseCommitTemplate(tmplPaths.Dir, tmplPaths.Scope, tmplPaths.TemplateFile, c.Paths.GlobalTemplateFile)
+	if err != nil || path == "" {
+		return "", err
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read previous commit template: %w", err)
+	}
+	return string(b), nil
+}
+
+// formatRemaining formats a duration to the minute, rounding up so that a
+// session never shows as having no time left.
+func formatRemaining(d time.Duration) string {
+	d = (d + time.Minute - 1).Truncate(time.Minute)
+	return strings.TrimSuffix(d.String(), "0s")
+}
+
+func scopeName(scope repository.Scope) string {
+	if scope == repository.ScopeLocal {
+		return "repository"
+	}
+	return string(scope)
+}
+
 func uniqueStrings(ids []string) []string {
 	var (
 		uniq = make(map[string]bool)
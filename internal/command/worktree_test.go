@@ -0,0 +1,89 @@
+package command
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorktree_SharedTemplate(t *testing.T) {
+	main := New(newWorkspace(t))
+	linked := New(newWorktree(t, main.Paths))
+
+	err := main.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = linked.TemplateSet("brett")
+	require.NoError(t, err)
+
+	mainPaths, err := main.Paths.Repository()
+	require.NoError(t, err)
+	linkedPaths, err := linked.Paths.Repository()
+	require.NoError(t, err)
+	require.Equal(t, mainPaths.TemplateFile, linkedPaths.TemplateFile)
+	require.NotEqual(t, mainPaths.GitDir, linkedPaths.GitDir)
+
+	// Activating in one worktree activates in all of them
+	out := bytes.NewBuffer(nil)
+	err = main.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "brett")
+}
+
+func TestWorktree_PerWorktreeTemplate(t *testing.T) {
+	main := New(newWorkspace(t))
+	linked := New(newWorktree(t, main.Paths))
+	runGit(t, main.Paths.WorkDir, "config", "partner.perWorktree", "true")
+
+	err := main.ManifestAdd("brett", "Brett Buddin", "brett@buddin.org")
+	require.NoError(t, err)
+	err = linked.TemplateSet("brett")
+	require.NoError(t, err)
+
+	linkedPaths, err := linked.Paths.Repository()
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(linkedPaths.GitDir, "gitmessage.txt"), linkedPaths.TemplateFile)
+	require.Equal(t, linkedPaths.TemplateFile, runGit(t, linked.Paths.WorkDir, "config", "commit.template"))
+
+	// The main worktree is unaffected
+	out := bytes.NewBuffer(nil)
+	err = main.TemplateStatus(out)
+	require.NoError(t, err)
+	require.Equal(t, "", out.String())
+	_, err = runGitErr(main.Paths.WorkDir, "config", "commit.template")
+	require.Error(t, err)
+
+	err = linked.TemplateClear()
+	require.NoError(t, err)
+	_, err = runGitErr(linked.Paths.WorkDir, "config", "commit.template")
+	require.Error(t, err)
+}
+
+// newWorktree adds a linked worktree to the repository in paths
+func newWorktree(t *testing.T, paths Paths) Paths {
+	t.Helper()
+
+	runGit(t, paths.WorkDir, "-c", "user.name=partner", "-c", "user.email=partner@example.com", "commit", "--allow-empty", "-m", "Initial commit")
+	dir := filepath.Join(paths.WorkDir, "linked")
+	runGit(t, paths.WorkDir, "worktree", "add", dir)
+
+	paths.WorkDir = dir
+	return paths
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := runGitErr(dir, args...)
+	require.NoError(t, err, out)
+	return out
+}
+
+func runGitErr(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
@@ -0,0 +1,62 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package lockedfile
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// staleLock is how long a lock file may go without its holder's PID before it
+// is assumed to have been left behind by a partner process that died before
+// writing it.
+const staleLock = 30 * time.Second
+
+// lock falls back to creating the lock file exclusively on platforms without
+// flock(2), polling until whoever holds it removes it. The holder's PID is
+// written to the lock file so that a lock left behind by a process that died
+// while holding it can be taken over, however long the lock has been held.
+func lock(path string) (func() error, error) {
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = f.WriteString(strconv.Itoa(os.Getpid()))
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, err
+			}
+			return func() error { return os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if stale(path) {
+			os.Remove(path)
+			continue
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// stale reports whether the process holding the lock file has exited
+func stale(path string) bool {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		// The holder hasn't written its PID yet, or died before it could
+		info, err := os.Stat(path)
+		return err == nil && time.Since(info.ModTime()) > staleLock
+	}
+	return !processAlive(pid)
+}
@@ -0,0 +1,61 @@
+// Package lockedfile writes files atomically and serializes read-modify-write
+// cycles on them across goroutines and processes.
+package lockedfile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile replaces the contents of a file atomically. The data is written
+// to a temporary file in the same directory, which is then renamed over the
+// destination, so readers see either the old contents or the new contents in
+// full, even if partner is interrupted.
+func WriteFile(path string, data []byte, perm os.FileMode) (err error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Lock takes an advisory lock guarding path, waiting for any other holder to
+// release it. The lock is held on a separate path+".lock" file, so that path
+// itself can be replaced by WriteFile while the lock is held. The returned
+// function releases the lock.
+func Lock(path string) (unlock func() error, err error) {
+	lockPath := path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lockPath), os.ModePerm); err != nil {
+		return nil, err
+	}
+	release, err := lock(lockPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return release, nil
+}
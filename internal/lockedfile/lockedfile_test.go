@@ -0,0 +1,86 @@
+package lockedfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lockedfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "nested", "file.json")
+	err = WriteFile(path, []byte("first"), 0644)
+	require.NoError(t, err)
+	err = WriteFile(path, []byte("second"), 0600)
+	require.NoError(t, err)
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "second", string(b))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// No temporary files are left behind
+	entries, err := ioutil.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lockedfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "counter")
+	err = WriteFile(path, []byte("0"), 0644)
+	require.NoError(t, err)
+
+	const n = 50
+	var (
+		wg   sync.WaitGroup
+		errs = make(chan error, n)
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- increment(path)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, strconv.Itoa(n), string(b))
+}
+
+func increment(path string) error {
+	unlock, err := Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	v, err := strconv.Atoi(string(b))
+	if err != nil {
+		return err
+	}
+	return WriteFile(path, []byte(strconv.Itoa(v+1)), 0644)
+}
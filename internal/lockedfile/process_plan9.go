@@ -0,0 +1,12 @@
+package lockedfile
+
+import (
+	"os"
+	"strconv"
+)
+
+// processAlive reports whether a process with the PID is running
+func processAlive(pid int) bool {
+	_, err := os.Stat("/proc/" + strconv.Itoa(pid))
+	return err == nil
+}
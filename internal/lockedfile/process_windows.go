@@ -0,0 +1,14 @@
+package lockedfile
+
+import "os"
+
+// processAlive reports whether a process with the PID is running. FindProcess
+// opens the process, which fails once it has exited.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,141 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lookup finds a coauthor by their ID or one of their aliases. IDs take
+// precedence over aliases.
+func (m *Manifest) Lookup(idOrAlias string) (Coauthor, bool) {
+	key, ok := m.resolve(idOrAlias)
+	if !ok {
+		return Coauthor{}, false
+	}
+	return m.Coauthors[key], true
+}
+
+// resolve returns the key of the coauthor with an ID or alias
+func (m *Manifest) resolve(idOrAlias string) (string, bool) {
+	key := strings.ToLower(idOrAlias)
+	if _, ok := m.Coauthors[key]; ok {
+		return key, true
+	}
+	return m.aliasOwner(idOrAlias)
+}
+
+// aliasOwner returns the key of the coauthor with an alias
+func (m *Manifest) aliasOwner(alias string) (string, bool) {
+	for key, ca := range m.Coauthors {
+		if containsFold(ca.Aliases, alias) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// checkAliases reports an alias that is also the ID or alias of another
+// coauthor. Coauthors are checked in the order given, so the same conflict is
+// always reported.
+func (m *Manifest) checkAliases(order []string) error {
+	owners := map[string]string{}
+	for key := range m.Coauthors {
+		owners[key] = key
+	}
+	for _, key := range order {
+		ca := m.Coauthors[key]
+		for _, alias := range ca.Aliases {
+			lower := strings.ToLower(alias)
+			owner, ok := owners[lower]
+			switch {
+			case ok && owner == lower && owner != key:
+				other := m.Coauthors[owner]
+				return fmt.Errorf("alias %q of coauthor %q%s is already the ID of coauthor %q%s", alias, ca.ID, sourceSuffix(ca), other.ID, sourceSuffix(other))
+			case ok && owner != key:
+				other := m.Coauthors[owner]
+				return fmt.Errorf("alias %q is used by both coauthor %q%s and coauthor %q%s", alias, other.ID, sourceSuffix(other), ca.ID, sourceSuffix(ca))
+			}
+			owners[lower] = key
+		}
+	}
+	return nil
+}
+
+// dropShadowedAliases removes aliases that are another coauthor's ID, or that
+// a coauthor earlier in order already uses, so that every alias refers to
+// exactly one coauthor.
+func (m *Manifest) dropShadowedAliases(order []string) {
+	owners := map[string]string{}
+	for key := range m.Coauthors {
+		owners[key] = key
+	}
+	for _, key := range order {
+		ca := m.Coauthors[key]
+		var kept []string
+		for _, alias := range ca.Aliases {
+			lower := strings.ToLower(alias)
+			if owner, ok := owners[lower]; ok && owner != key {
+				continue
+			}
+			owners[lower] = key
+			kept = append(kept, alias)
+		}
+		if len(kept) != len(ca.Aliases) {
+			ca.Aliases = kept
+			m.Coauthors[key] = ca
+		}
+	}
+}
+
+func sourceSuffix(ca Coauthor) string {
+	if ca.Source == "" {
+		return ""
+	}
+	return " in the " + ca.Source + " manifest"
+}
+
+// AddAlias gives a coauthor shorter names to refer to them by. Aliases must
+// not be the ID or alias of any other coauthor.
+func (m *Manifest) AddAlias(id string, aliases ...string) error {
+	key, ok := m.resolve(id)
+	if !ok {
+		return fmt.Errorf("unknown coauthor %q", id)
+	}
+	ca := m.Coauthors[key]
+	for _, alias := range aliases {
+		if err := validateID(alias); err != nil {
+			return err
+		}
+		if other, ok := m.Coauthors[strings.ToLower(alias)]; ok {
+			return fmt.Errorf("alias %q is already the ID of coauthor %q", alias, other.ID)
+		}
+		if owner, ok := m.aliasOwner(alias); ok && owner != key {
+			return fmt.Errorf("alias %q is already used by coauthor %q", alias, m.Coauthors[owner].ID)
+		}
+		if !containsFold(ca.Aliases, alias) {
+			ca.Aliases = append(ca.Aliases, alias)
+		}
+	}
+	m.Coauthors[key] = ca
+	return nil
+}
+
+// RemoveAlias removes aliases from whichever coauthor has them
+func (m *Manifest) RemoveAlias(aliases ...string) error {
+	for _, alias := range aliases {
+		key, ok := m.aliasOwner(alias)
+		if !ok {
+			return fmt.Errorf("unknown alias %q", alias)
+		}
+		ca := m.Coauthors[key]
+		var kept []string
+		for _, a := range ca.Aliases {
+			if !strings.EqualFold(a, alias) {
+				kept = append(kept, a)
+			}
+		}
+		ca.Aliases = kept
+		m.Coauthors[key] = ca
+	}
+	return nil
+}
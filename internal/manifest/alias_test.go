@@ -0,0 +1,99 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAliases(t *testing.T) {
+	m, err := Load("testdata/manifest.json")
+	require.NoError(t, err)
+
+	err = m.AddAlias("stuartcarnie", "sc", "stu")
+	require.NoError(t, err)
+	err = m.AddAlias("GeorgeMac", "gm")
+	require.NoError(t, err)
+	require.Equal(t, []string{"sc", "stu"}, m.Coauthors["stuartcarnie"].Aliases)
+
+	// Aliases resolve wherever IDs do
+	coauthors, err := m.Find("SC", "gm")
+	require.NoError(t, err)
+	require.Len(t, coauthors, 2)
+	require.Equal(t, "GeorgeMac", coauthors[0].ID)
+	require.Equal(t, "stuartcarnie", coauthors[1].ID)
+
+	err = m.SetGroup("platform", "sc", "gm", "stuartcarnie")
+	require.NoError(t, err)
+	require.Equal(t, []string{"stuartcarnie", "GeorgeMac"}, m.Groups["platform"].Members)
+
+	// Collisions with IDs and other coauthors' aliases
+	err = m.AddAlias("stuartcarnie", "gavincabbage")
+	require.EqualError(t, err, `alias "gavincabbage" is already the ID of coauthor "gavincabbage"`)
+	err = m.AddAlias("gavincabbage", "SC")
+	require.EqualError(t, err, `alias "SC" is already used by coauthor "stuartcarnie"`)
+	err = m.AddAlias("stuartcarnie", "sc")
+	require.NoError(t, err)
+	err = m.AddAlias("stuartcarnie", "@sc")
+	require.Error(t, err)
+	err = m.Add(Coauthor{ID: "sc", Name: "Someone Else", Email: "sc@example.com"})
+	require.EqualError(t, err, `"sc" is already an alias of coauthor "stuartcarnie"`)
+	_, err = m.Edit("gavincabbage", Edit{ID: "gm"})
+	require.EqualError(t, err, `"gm" is already an alias of coauthor "GeorgeMac"`)
+
+	err = m.RemoveAlias("stu")
+	require.NoError(t, err)
+	require.Equal(t, []string{"sc"}, m.Coauthors["stuartcarnie"].Aliases)
+	err = m.RemoveAlias("stu")
+	require.EqualError(t, err, `unknown alias "stu"`)
+
+	// Removing by alias removes the coauthor from their groups
+	err = m.Remove("sc")
+	require.NoError(t, err)
+	_, ok := m.Lookup("stuartcarnie")
+	require.False(t, ok)
+	require.Equal(t, []string{"GeorgeMac"}, m.Groups["platform"].Members)
+}
+
+func TestLoadLayers_AliasConflicts(t *testing.T) {
+	user, err := Load("testdata/manifest.json")
+	require.NoError(t, err)
+	repo, err := Load("testdata/repository.json")
+	require.NoError(t, err)
+	layers := []Layer{{Source: SourceUser, Manifest: user}, {Source: SourceRepository, Manifest: repo}}
+
+	err = user.AddAlias("stuartcarnie", "sc")
+	require.NoError(t, err)
+	err = CheckAliases(layers...)
+	require.NoError(t, err)
+
+	// Aliases can't be IDs in another layer
+	err = user.AddAlias("stuartcarnie", "brettbuddin")
+	require.NoError(t, err)
+	err = CheckAliases(layers...)
+	require.EqualError(t, err, `alias "brettbuddin" of coauthor "stuartcarnie" in the user manifest is already the ID of coauthor "brettbuddin" in the repository manifest`)
+
+	// but loading still works, with the ID taking precedence
+	m, err := LoadLayers(layers...)
+	require.NoError(t, err)
+	ca, ok := m.Lookup("brettbuddin")
+	require.True(t, ok)
+	require.Equal(t, "brettbuddin", ca.ID)
+	require.Equal(t, []string{"sc"}, m.Coauthors["stuartcarnie"].Aliases)
+	err = user.RemoveAlias("brettbuddin")
+	require.NoError(t, err)
+
+	// nor aliases of another coauthor
+	err = repo.AddAlias("brettbuddin", "sc")
+	require.NoError(t, err)
+	err = CheckAliases(layers...)
+	require.EqualError(t, err, `alias "sc" is used by both coauthor "brettbuddin" in the repository manifest and coauthor "stuartcarnie" in the user manifest`)
+
+	// in which case the later layer takes precedence
+	m, err = LoadLayers(layers...)
+	require.NoError(t, err)
+	ca, ok = m.Lookup("sc")
+	require.True(t, ok)
+	require.Equal(t, "brettbuddin", ca.ID)
+	require.Empty(t, m.Coauthors["stuartcarnie"].Aliases)
+}
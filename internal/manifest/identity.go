@@ -0,0 +1,138 @@
+package manifest
+
+import (
+	"fmt"
+	"net/mail"
+	"path"
+	"strings"
+)
+
+// Identity is an additional email address a coauthor can be credited with.
+// Which identity is credited depends on the repository: identities can be
+// selected by label with the partner.identity git configuration, or by the
+// host of the repository's remote.
+type Identity struct {
+	Label string `json:"label,omitempty"`
+	Email string `json:"email"`
+	// Hosts are patterns, as understood by path.Match, for the remote hosts
+	// of repositories the identity is used in.
+	Hosts []string `json:"hosts,omitempty"`
+}
+
+// Emails returns every email address of a coauthor, the default one first
+func (ca Coauthor) Emails() []string {
+	emails := []string{ca.Email}
+	for _, ident := range ca.Identities {
+		emails = append(emails, ident.Email)
+	}
+	return emails
+}
+
+// Select returns the coauthor with the email address of the identity to
+// credit them with. An identity with a matching label is preferred, then one
+// matching the remote host. Otherwise the default email address is kept.
+//
+// The default email address takes the place of the selected identity, so that
+// Emails still returns every address of the coauthor.
+func (ca Coauthor) Select(label, host string) Coauthor {
+	i := ca.selectIdentity(label, host)
+	if i < 0 {
+		return ca
+	}
+	identities := append([]Identity(nil), ca.Identities...)
+	identities[i] = Identity{Email: ca.Email}
+	ca.Email = ca.Identities[i].Email
+	ca.Identities = identities
+	return ca
+}
+
+func (ca Coauthor) selectIdentity(label, host string) int {
+	if label != "" {
+		for i, ident := range ca.Identities {
+			if strings.EqualFold(ident.Label, label) {
+				return i
+			}
+		}
+	}
+	if host != "" {
+		for i, ident := range ca.Identities {
+			for _, pattern := range ident.Hosts {
+				if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(host)); ok {
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// AddIdentity adds an identity to a coauthor, replacing any identity with the
+// same email address
+func (m *Manifest) AddIdentity(id string, ident Identity) error {
+	key, ok := m.resolve(id)
+	if !ok {
+		return fmt.Errorf("unknown coauthor %q", id)
+	}
+	ca := m.Coauthors[key]
+	if err := validateIdentity(ident); err != nil {
+		return err
+	}
+	if strings.EqualFold(ident.Email, ca.Email) {
+		return fmt.Errorf("%s is already the default email address of coauthor %q", ident.Email, ca.ID)
+	}
+
+	var identities []Identity
+	for _, existing := range ca.Identities {
+		if ident.Label != "" && strings.EqualFold(existing.Label, ident.Label) && !strings.EqualFold(existing.Email, ident.Email) {
+			return fmt.Errorf("coauthor %q already has an identity labeled %q", ca.ID, ident.Label)
+		}
+		if !strings.EqualFold(existing.Email, ident.Email) {
+			identities = append(identities, existing)
+		}
+	}
+	ca.Identities = append(identities, ident)
+	m.Coauthors[key] = ca
+	return nil
+}
+
+// RemoveIdentity removes an identity from a coauthor by its email address or
+// label
+func (m *Manifest) RemoveIdentity(id, emailOrLabel string) error {
+	key, ok := m.resolve(id)
+	if !ok {
+		return fmt.Errorf("unknown coauthor %q", id)
+	}
+	ca := m.Coauthors[key]
+	var (
+		identities []Identity
+		found      bool
+	)
+	for _, ident := range ca.Identities {
+		if strings.EqualFold(ident.Email, emailOrLabel) || (ident.Label != "" && strings.EqualFold(ident.Label, emailOrLabel)) {
+			found = true
+			continue
+		}
+		identities = append(identities, ident)
+	}
+	if !found {
+		return fmt.Errorf("coauthor %q has no identity %q", ca.ID, emailOrLabel)
+	}
+	ca.Identities = identities
+	m.Coauthors[key] = ca
+	return nil
+}
+
+func validateIdentity(ident Identity) error {
+	if addr, err := mail.ParseAddress(ident.Email); err != nil || addr.Address != ident.Email {
+		return fmt.Errorf("invalid email address %q", ident.Email)
+	}
+	if strings.ContainsAny(ident.Label, " \t\n,") {
+		return fmt.Errorf("invalid identity label %q", ident.Label)
+	}
+	for _, pattern := range ident.Hosts {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("invalid host pattern %q", pattern)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelect(t *testing.T) {
+	ca := Coauthor{
+		ID:    "brett",
+		Name:  "Brett Buddin",
+		Email: "6059+brettbuddin@users.noreply.github.com",
+		Identities: []Identity{
+			{Label: "work", Email: "brett@work.example.com", Hosts: []string{"gitlab.work.example.com", "*.corp.example.com"}},
+			{Label: "personal", Email: "brett@buddin.org"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		label    string
+		host     string
+		expected string
+	}{
+		{name: "default", host: "github.com", expected: "6059+brettbuddin@users.noreply.github.com"},
+		{name: "host", host: "gitlab.work.example.com", expected: "brett@work.example.com"},
+		{name: "host pattern", host: "git.CORP.example.com", expected: "brett@work.example.com"},
+		{name: "label", label: "personal", host: "gitlab.work.example.com", expected: "brett@buddin.org"},
+		{name: "unknown label", label: "unknown", host: "gitlab.work.example.com", expected: "brett@work.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected := ca.Select(tt.label, tt.host)
+			require.Equal(t, tt.expected, selected.Email)
+			require.ElementsMatch(t, ca.Emails(), selected.Emails())
+		})
+	}
+}
+
+func TestIdentities(t *testing.T) {
+	m, err := Load("testdata/manifest.json")
+	require.NoError(t, err)
+
+	err = m.AddIdentity("georgemac", Identity{Label: "work", Email: "george@work.example.com", Hosts: []string{"gitlab.work.example.com"}})
+	require.NoError(t, err)
+	err = m.AddIdentity("georgemac", Identity{Label: "work", Email: "george@other.example.com"})
+	require.EqualError(t, err, `coauthor "GeorgeMac" already has an identity labeled "work"`)
+	err = m.AddIdentity("georgemac", Identity{Email: "1253326+GeorgeMac@users.noreply.github.com"})
+	require.Error(t, err)
+	err = m.AddIdentity("georgemac", Identity{Email: "not an email"})
+	require.EqualError(t, err, `invalid email address "not an email"`)
+	err = m.AddIdentity("georgemac", Identity{Email: "george@example.com", Hosts: []string{"["}})
+	require.EqualError(t, err, `invalid host pattern "["`)
+
+	// Adding an existing address replaces it
+	err = m.AddIdentity("georgemac", Identity{Label: "work", Email: "george@work.example.com"})
+	require.NoError(t, err)
+	require.Equal(t, []Identity{{Label: "work", Email: "george@work.example.com"}}, m.Coauthors["georgemac"].Identities)
+
+	err = m.RemoveIdentity("georgemac", "personal")
+	require.Error(t, err)
+	err = m.RemoveIdentity("georgemac", "work")
+	require.NoError(t, err)
+	require.Empty(t, m.Coauthors["georgemac"].Identities)
+}
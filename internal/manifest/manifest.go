@@ -4,17 +4,45 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/mail"
 	"os"
-	"path/filepath"
 	"sort"
 	"strings"
+
+	"github.com/brettbuddin/partner/internal/lockedfile"
 )
 
 const DefaultPath = "~/.config/partner/manifest.json"
 
+// RepositoryFile is the name of the manifest a repository can commit at its
+// root to share coauthors with everyone working in it
+const RepositoryFile = ".partner.json"
+
+// Sources of coauthors in a layered manifest
+const (
+	SourceUser       = "user"
+	SourceRepository = "repository"
+)
+
+// Layer is a manifest file that contributes coauthors to a layered manifest
+type Layer struct {
+	Source string
+	Path   string
+	// Manifest is used in place of the file at Path, if set, such as when
+	// checking changes before they're written.
+	Manifest *Manifest
+}
+
+// GroupPrefix marks a reference to a group rather than a single coauthor,
+// e.g. "@platform"
+const GroupPrefix = "@"
+
 // Manifest contains all coauthors
 type Manifest struct {
+	Version   int                 `json:"version"`
 	Coauthors map[string]Coauthor `json:"coauthors"`
+	Groups    map[string]Group    `json:"groups,omitempty"`
 }
 
 func (m Manifest) Slice() []Coauthor {
@@ -31,28 +59,52 @@ type Coauthor struct {
 	Type  string `json:"type"`
 	Name  string `json:"name"`
 	Email string `json:"email"`
+	// Host is the instance of the service the coauthor was fetched from, such
+	// as a GitHub Enterprise Server. Coauthors fetched before hosts were
+	// recorded come from the public instances.
+	Host string `json:"host,omitempty"`
+	// Identities are email addresses to credit the coauthor with instead of
+	// Email in some repositories.
+	Identities []Identity `json:"identities,omitempty"`
+	// Aliases are other names the coauthor can be referred to by.
+	Aliases []string `json:"aliases,omitempty"`
+	// Source is the layer the coauthor was loaded from, if any.
+	Source string `json:"-"`
+}
+
+// Group is a named set of coauthors that can be activated together
+type Group struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
 }
 
 // Coauthor types
 const (
-	CoauthorTypeGitHub = "github"
-	CoauthorTypeGitLab = "gitlab"
-	CoauthorTypeManual = "manual"
+	CoauthorTypeGitHub    = "github"
+	CoauthorTypeGitLab    = "gitlab"
+	CoauthorTypeBitbucket = "bitbucket"
+	CoauthorTypeGitea     = "gitea"
+	CoauthorTypeGerrit    = "gerrit"
+	CoauthorTypeManual    = "manual"
+	CoauthorTypeGit       = "git"
 )
 
-// Load reads a Manifest
+// Load reads a Manifest, migrating it to the current version if it was
+// written by an older version of partner
 func Load(path string) (*Manifest, error) {
-	f, err := os.Open(path)
+	b, err := ioutil.ReadFile(path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return &Manifest{}, nil
+			return &Manifest{Version: CurrentVersion}, nil
 		}
 		return nil, err
 	}
-	defer f.Close()
+	if b, err = migrate(b); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
 
 	var m Manifest
-	if err := json.NewDecoder(f).Decode(&m); err != nil {
+	if err := json.Unmarshal(b, &m); err != nil {
 		return nil, err
 	}
 	if m.Coauthors == nil {
@@ -61,15 +113,104 @@ func Load(path string) (*Manifest, error) {
 	return &m, nil
 }
 
-// Find looks up a list of coauthors by their IDs
+// LoadLayers reads several manifests and merges them into one. Coauthors and
+// groups in later layers take precedence over those with the same ID or name
+// in earlier layers. Every coauthor is tagged with the source of its layer.
+// Aliases follow the same precedence as Lookup: an alias that is another
+// coauthor's ID is dropped, as is one that a coauthor in a later layer also
+// uses. CheckAliases reports these conflicts.
+//
+// The result should not be written back to disk, since it would copy entries
+// from one layer into another.
+func LoadLayers(layers ...Layer) (*Manifest, error) {
+	merged, order, err := mergeLayers(layers...)
+	if err != nil {
+		return nil, err
+	}
+	merged.dropShadowedAliases(order)
+	return merged, nil
+}
+
+// CheckAliases merges several manifests like LoadLayers, and reports an alias
+// that is the ID or alias of another coauthor in any layer, since it couldn't
+// be told which of them it refers to.
+func CheckAliases(layers ...Layer) error {
+	merged, order, err := mergeLayers(layers...)
+	if err != nil {
+		return err
+	}
+	return merged.checkAliases(order)
+}
+
+// mergeLayers merges several manifests, returning the keys of the merged
+// coauthors in order of precedence: later layers first, and by key within a
+// layer.
+func mergeLayers(layers ...Layer) (*Manifest, []string, error) {
+	var (
+		merged = &Manifest{Coauthors: map[string]Coauthor{}}
+		rank   = map[string]int{}
+	)
+	for i, layer := range layers {
+		m := layer.Manifest
+		if m == nil {
+			var err error
+			if m, err = Load(layer.Path); err != nil {
+				return nil, nil, fmt.Errorf("failed to load %s manifest: %w", layer.Source, err)
+			}
+		}
+		for key, ca := range m.Coauthors {
+			ca.Source = layer.Source
+			merged.Coauthors[key] = ca
+			rank[key] = i
+		}
+		for key, g := range m.Groups {
+			if merged.Groups == nil {
+				merged.Groups = map[string]Group{}
+			}
+			merged.Groups[key] = g
+		}
+	}
+	var order []string
+	for key := range merged.Coauthors {
+		order = append(order, key)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if rank[order[i]] != rank[order[j]] {
+			return rank[order[i]] > rank[order[j]]
+		}
+		return order[i] < order[j]
+	})
+	return merged, order, nil
+}
+
+// Find looks up a list of coauthors by their IDs or aliases. References to
+// groups, such as "@platform", are expanded to the group's members.
 func (m *Manifest) Find(ids ...string) ([]Coauthor, error) {
-	var coauthors []Coauthor
+	var (
+		coauthors []Coauthor
+		seen      = map[string]bool{}
+	)
 	for _, id := range ids {
-		ca, ok := m.Coauthors[strings.ToLower(id)]
-		if !ok {
-			return nil, fmt.Errorf("unknown coauthor %q", id)
+		members := []string{id}
+		if strings.HasPrefix(id, GroupPrefix) {
+			g, ok := m.Groups[strings.ToLower(strings.TrimPrefix(id, GroupPrefix))]
+			if !ok {
+				return nil, fmt.Errorf("unknown group %q", id)
+			}
+			members = g.Members
+		}
+		for _, member := range members {
+			key, ok := m.resolve(member)
+			if !ok {
+				return nil, fmt.Errorf("unknown coauthor %q", member)
+			}
+			ca := m.Coauthors[key]
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			coauthors = append(coauthors, ca)
 		}
-		coauthors = append(coauthors, ca)
 	}
 	sort.Slice(coauthors, func(i, j int) bool {
 		return strings.ToLower(coauthors[i].ID) < strings.ToLower(coauthors[j].ID)
@@ -77,21 +218,248 @@ func (m *Manifest) Find(ids ...string) ([]Coauthor, error) {
 	return coauthors, nil
 }
 
-// Remove removes coauthors by their IDs
+// Remove removes coauthors by their IDs or aliases. They are also removed from
+// any group they belong to, and groups left without members are removed
+// entirely.
 func (m *Manifest) Remove(ids ...string) error {
 	if m.Coauthors == nil {
 		m.Coauthors = map[string]Coauthor{}
 	}
 	for _, id := range ids {
-		key := strings.ToLower(id)
-		if _, ok := m.Coauthors[key]; !ok {
+		key, ok := m.resolve(id)
+		if !ok {
 			return fmt.Errorf("unknown coauthor %q", id)
 		}
+		ca := m.Coauthors[key]
 		delete(m.Coauthors, key)
+		for _, name := range m.GroupsContaining(ca.ID) {
+			m.removeMembers(strings.ToLower(name), ca.ID)
+		}
+	}
+	return nil
+}
+
+// Edit describes changes to a coauthor. Empty fields are left unchanged.
+type Edit struct {
+	ID    string
+	Name  string
+	Email string
+}
+
+// Edit changes a coauthor in place. When the ID changes, the groups the
+// coauthor belongs to are updated to match.
+func (m *Manifest) Edit(id string, ed Edit) (Coauthor, error) {
+	key, ok := m.resolve(id)
+	if !ok {
+		return Coauthor{}, fmt.Errorf("unknown coauthor %q", id)
+	}
+	ca := m.Coauthors[key]
+	oldID := ca.ID
+	// Only the changed fields are validated. Coauthors fetched without a name
+	// can still be edited.
+	if ed.ID != "" {
+		if err := validateID(ed.ID); err != nil {
+			return Coauthor{}, err
+		}
+		ca.ID = ed.ID
+	}
+	if ed.Name != "" {
+		if err := validateName(ca.ID, ed.Name); err != nil {
+			return Coauthor{}, err
+		}
+		ca.Name = ed.Name
+	}
+	if ed.Email != "" {
+		if err := validateEmail(ca.ID, ed.Email); err != nil {
+			return Coauthor{}, err
+		}
+		ca.Email = ed.Email
+	}
+
+	newKey := strings.ToLower(ca.ID)
+	if _, ok := m.Coauthors[newKey]; ok && newKey != key {
+		return Coauthor{}, fmt.Errorf("coauthor with ID %q already exists", ca.ID)
+	}
+	if owner, ok := m.aliasOwner(ca.ID); ok && owner != key {
+		return Coauthor{}, fmt.Errorf("%q is already an alias of coauthor %q", ca.ID, m.Coauthors[owner].ID)
+	}
+	delete(m.Coauthors, key)
+	m.Coauthors[newKey] = ca
+
+	for _, name := range m.GroupsContaining(oldID) {
+		g := m.Groups[strings.ToLower(name)]
+		for i, member := range g.Members {
+			if strings.EqualFold(member, oldID) {
+				g.Members[i] = ca.ID
+			}
+		}
+	}
+	return ca, nil
+}
+
+// GroupsContaining returns the names of the groups a coauthor belongs to
+func (m *Manifest) GroupsContaining(id string) []string {
+	var names []string
+	for _, g := range m.Groups {
+		if containsFold(g.Members, id) {
+			names = append(names, g.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GroupSlice returns all groups, sorted by name
+func (m Manifest) GroupSlice() []Group {
+	var l []Group
+	for _, g := range m.Groups {
+		l = append(l, g)
+	}
+	sort.Slice(l, func(i, j int) bool {
+		return strings.ToLower(l[i].Name) < strings.ToLower(l[j].Name)
+	})
+	return l
+}
+
+// AddToGroup adds coauthors to a group, creating the group if it doesn't
+// exist yet
+func (m *Manifest) AddToGroup(name string, ids ...string) error {
+	return m.AddToGroupFrom(m, name, ids...)
+}
+
+// AddToGroupFrom is AddToGroup, with the coauthors looked up in another
+// manifest, such as the layered manifest the group is used from
+func (m *Manifest) AddToGroupFrom(from *Manifest, name string, ids ...string) error {
+	g, ok := m.Groups[strings.ToLower(name)]
+	if !ok {
+		g = Group{Name: name}
+	}
+	return m.SetGroupFrom(from, g.Name, append(g.Members, ids...)...)
+}
+
+// SetGroup creates a group or replaces its members
+func (m *Manifest) SetGroup(name string, ids ...string) error {
+	return m.SetGroupFrom(m, name, ids...)
+}
+
+// SetGroupFrom is SetGroup, with the coauthors looked up in another manifest,
+// such as the layered manifest the group is used from
+func (m *Manifest) SetGroupFrom(from *Manifest, name string, ids ...string) error {
+	if err := validateGroupName(name); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("group %q must have at least one member", name)
+	}
+	var members []string
+	for _, id := range ids {
+		ca, ok := from.Lookup(id)
+		if !ok {
+			return fmt.Errorf("unknown coauthor %q", id)
+		}
+		if !containsFold(members, ca.ID) {
+			members = append(members, ca.ID)
+		}
+	}
+	if m.Groups == nil {
+		m.Groups = map[string]Group{}
+	}
+	m.Groups[strings.ToLower(name)] = Group{Name: name, Members: members}
+	return nil
+}
+
+// RemoveFromGroup removes coauthors from a group. The group is removed
+// entirely once it has no members left.
+func (m *Manifest) RemoveFromGroup(name string, ids ...string) error {
+	key := strings.ToLower(name)
+	g, ok := m.Groups[key]
+	if !ok {
+		return fmt.Errorf("unknown group %q", name)
+	}
+	var members []string
+	for _, id := range ids {
+		if ca, ok := m.Lookup(id); ok {
+			id = ca.ID
+		}
+		if !containsFold(g.Members, id) {
+			return fmt.Errorf("coauthor %q is not a member of group %q", id, g.Name)
+		}
+		members = append(members, id)
+	}
+	m.removeMembers(key, members...)
+	return nil
+}
+
+// RemoveGroups removes groups by name. The coauthors in them are kept.
+func (m *Manifest) RemoveGroups(names ...string) error {
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if _, ok := m.Groups[key]; !ok {
+			return fmt.Errorf("unknown group %q", name)
+		}
+		delete(m.Groups, key)
 	}
 	return nil
 }
 
+func (m *Manifest) removeMembers(key string, ids ...string) {
+	g := m.Groups[key]
+	var members []string
+	for _, member := range g.Members {
+		if !containsFold(ids, member) {
+			members = append(members, member)
+		}
+	}
+	if len(members) == 0 {
+		delete(m.Groups, key)
+		return
+	}
+	g.Members = members
+	m.Groups[key] = g
+}
+
+// validateName checks that a name can be credited in a trailer, which has no
+// room for angle brackets or line breaks
+func validateName(id, name string) error {
+	if strings.TrimSpace(name) == "" || strings.ContainsAny(name, "<>\n") {
+		return fmt.Errorf("invalid name %q for coauthor %q", name, id)
+	}
+	return nil
+}
+
+func validateEmail(id, email string) error {
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return fmt.Errorf("invalid email address %q for coauthor %q", email, id)
+	}
+	return nil
+}
+
+func validateID(id string) error {
+	switch {
+	case id == "" || strings.ContainsAny(id, ", \t\n"):
+		return fmt.Errorf("invalid coauthor ID %q", id)
+	case strings.HasPrefix(id, GroupPrefix):
+		return fmt.Errorf("coauthor ID %q cannot start with %q", id, GroupPrefix)
+	}
+	return nil
+}
+
+func validateGroupName(name string) error {
+	if name == "" || strings.HasPrefix(name, GroupPrefix) || strings.ContainsAny(name, ", \t") {
+		return fmt.Errorf("invalid group name %q", name)
+	}
+	return nil
+}
+
+func containsFold(l []string, s string) bool {
+	for _, v := range l {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
 // Add adds coauthors to the Manifest
 func (m *Manifest) Add(coauthors ...Coauthor) error {
 	if m.Coauthors == nil {
@@ -99,33 +467,42 @@ func (m *Manifest) Add(coauthors ...Coauthor) error {
 	}
 	for _, newCA := range coauthors {
 		key := strings.ToLower(newCA.ID)
+		if err := validateID(newCA.ID); err != nil {
+			return err
+		}
 		if _, ok := m.Coauthors[key]; ok {
 			return fmt.Errorf("coauthor with ID %q already exists", newCA.ID)
 		}
+		if owner, ok := m.aliasOwner(newCA.ID); ok {
+			return fmt.Errorf("%q is already an alias of coauthor %q", newCA.ID, m.Coauthors[owner].ID)
+		}
 		m.Coauthors[key] = newCA
 	}
 	return nil
 }
 
-// WriteFile saves the manifest to a JSON file
+// WriteFile saves the manifest to a JSON file. A manifest written by a newer
+// version of partner is never overwritten.
 func WriteFile(path string, m *Manifest) error {
-	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
-		return err
-	}
-	f, err := os.Create(path)
+	existing, err := FileVersion(path)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	if existing > CurrentVersion {
+		return NewerVersionError{Path: path, Version: existing}
+	}
+	if m.Version > CurrentVersion {
+		return NewerVersionError{Path: path, Version: m.Version}
+	}
+	m.Version = CurrentVersion
 
 	if m.Coauthors == nil {
 		m.Coauthors = map[string]Coauthor{}
 	}
 
-	e := json.NewEncoder(f)
-	e.SetIndent("", "  ")
-	if err := e.Encode(m); err != nil {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
 		return err
 	}
-	return nil
+	return lockedfile.WriteFile(path, append(b, '\n'), 0644)
 }
@@ -152,3 +152,137 @@ func TestSave(t *testing.T) {
 		},
 	}, cm.Coauthors)
 }
+
+func TestGroups(t *testing.T) {
+	m, err := Load("testdata/manifest.json")
+	require.NoError(t, err)
+
+	err = m.AddToGroup("platform", "GeorgeMac", "gavincabbage")
+	require.NoError(t, err)
+	err = m.AddToGroup("Platform", "georgemac", "stuartcarnie")
+	require.NoError(t, err)
+	require.Equal(t, map[string]Group{
+		"platform": {Name: "platform", Members: []string{"GeorgeMac", "gavincabbage", "stuartcarnie"}},
+	}, m.Groups)
+
+	err = m.SetGroup("platform", "unknown")
+	require.Error(t, err)
+	err = m.SetGroup("@platform", "gavincabbage")
+	require.Error(t, err)
+
+	// Groups expand to their members, without duplicates
+	coauthors, err := m.Find("@platform", "GeorgeMac")
+	require.NoError(t, err)
+	var ids []string
+	for _, ca := range coauthors {
+		ids = append(ids, ca.ID)
+	}
+	require.Equal(t, []string{"gavincabbage", "GeorgeMac", "stuartcarnie"}, ids)
+
+	_, err = m.Find("@unknown")
+	require.EqualError(t, err, `unknown group "@unknown"`)
+
+	// Removing a coauthor removes them from their groups
+	require.Equal(t, []string{"platform"}, m.GroupsContaining("georgemac"))
+	err = m.Remove("GeorgeMac")
+	require.NoError(t, err)
+	require.Equal(t, []string{"gavincabbage", "stuartcarnie"}, m.Groups["platform"].Members)
+
+	err = m.RemoveFromGroup("platform", "GeorgeMac")
+	require.Error(t, err)
+	err = m.RemoveFromGroup("platform", "gavincabbage", "stuartcarnie")
+	require.NoError(t, err)
+	require.Empty(t, m.Groups)
+}
+
+func TestLoadLayers(t *testing.T) {
+	m, err := LoadLayers(
+		Layer{Source: SourceUser, Path: "testdata/manifest.json"},
+		Layer{Source: SourceRepository, Path: "testdata/repository.json"},
+		Layer{Source: "missing", Path: "testdata/missing.json"},
+	)
+	require.NoError(t, err)
+
+	// The repository's entry for GeorgeMac takes precedence
+	require.Equal(t, map[string]Coauthor{
+		"georgemac": {
+			ID:     "GeorgeMac",
+			Name:   "George Mac",
+			Email:  "george@example.com",
+			Type:   "manual",
+			Source: SourceRepository,
+		},
+		"gavincabbage": {
+			ID:     "gavincabbage",
+			Name:   "Gavin Cabbage",
+			Email:  "5225414+gavincabbage@users.noreply.github.com",
+			Type:   "github",
+			Source: SourceUser,
+		},
+		"stuartcarnie": {
+			ID:     "stuartcarnie",
+			Name:   "Stuart Carnie",
+			Email:  "52852+stuartcarnie@users.noreply.github.com",
+			Type:   "github",
+			Source: SourceUser,
+		},
+		"brettbuddin": {
+			ID:     "brettbuddin",
+			Name:   "Brett Buddin",
+			Email:  "brett@buddin.org",
+			Type:   "manual",
+			Source: SourceRepository,
+		},
+	}, m.Coauthors)
+
+	coauthors, err := m.Find("@platform", "gavincabbage")
+	require.NoError(t, err)
+	require.Len(t, coauthors, 3)
+}
+
+func TestEdit(t *testing.T) {
+	m, err := Load("testdata/manifest.json")
+	require.NoError(t, err)
+	err = m.SetGroup("platform", "GeorgeMac", "gavincabbage")
+	require.NoError(t, err)
+
+	ca, err := m.Edit("georgemac", Edit{ID: "george", Email: "george@example.com"})
+	require.NoError(t, err)
+	require.Equal(t, Coauthor{
+		ID:    "george",
+		Name:  "George",
+		Email: "george@example.com",
+		Type:  "github",
+	}, ca)
+	require.Equal(t, ca, m.Coauthors["george"])
+	require.NotContains(t, m.Coauthors, "georgemac")
+	require.Equal(t, []string{"george", "gavincabbage"}, m.Groups["platform"].Members)
+
+	tests := []struct {
+		name string
+		id   string
+		edit Edit
+		err  string
+	}{
+		{name: "unknown", id: "unknown", edit: Edit{Name: "Unknown"}, err: `unknown coauthor "unknown"`},
+		{name: "existing ID", id: "george", edit: Edit{ID: "GavinCabbage"}, err: `coauthor with ID "GavinCabbage" already exists`},
+		{name: "group prefix", id: "george", edit: Edit{ID: "@george"}, err: `coauthor ID "@george" cannot start with "@"`},
+		{name: "whitespace in ID", id: "george", edit: Edit{ID: "george mac"}, err: `invalid coauthor ID "george mac"`},
+		{name: "name", id: "george", edit: Edit{Name: "George <Mac>"}, err: `invalid name "George <Mac>" for coauthor "george"`},
+		{name: "email", id: "george", edit: Edit{Email: "George <george@example.com>"}, err: `invalid email address "George <george@example.com>" for coauthor "george"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.Edit(tt.id, tt.edit)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+
+	// Coauthors without a name can still be edited
+	err = m.Add(Coauthor{ID: "nameless", Email: "nameless@example.com", Type: CoauthorTypeGitHub})
+	require.NoError(t, err)
+	ca, err = m.Edit("nameless", Edit{Email: "someone@example.com"})
+	require.NoError(t, err)
+	require.Equal(t, "someone@example.com", ca.Email)
+	require.Empty(t, ca.Name)
+}
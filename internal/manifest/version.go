@@ -0,0 +1,99 @@
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// CurrentVersion is the version of the manifest format written by this
+// version of partner. It only changes when existing data changes meaning:
+// optional fields that older versions of partner ignore don't need a new
+// version, which would keep those versions from writing the manifest at all.
+const CurrentVersion = 2
+
+// migrations upgrade the raw JSON of a manifest from the version they are
+// keyed by to the next one. Manifests written before the version field was
+// introduced are version 1.
+var migrations = map[int]func(raw map[string]json.RawMessage) error{
+	// Version 1 manifests have no version field, so a manifest still being
+	// written by a partner that predates versioning can't be told apart from
+	// a current one. Version 2 changes nothing else; migrating only records
+	// the version, which lets manifest migrate --check find such manifests.
+	1: func(raw map[string]json.RawMessage) error {
+		return nil
+	},
+}
+
+// NewerVersionError is returned when writing would replace a manifest written
+// by a newer version of partner, and lose whatever it added.
+type NewerVersionError struct {
+	Path    string
+	Version int
+}
+
+func (e NewerVersionError) Error() string {
+	return fmt.Sprintf("%s has manifest version %d, but this version of partner only supports up to version %d; upgrade partner to change it", e.Path, e.Version, CurrentVersion)
+}
+
+// FileVersion returns the manifest version of a file, without migrating it.
+// Missing files are reported as the current version.
+func FileVersion(path string) (int, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return CurrentVersion, nil
+		}
+		return 0, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return 0, err
+	}
+	return rawVersion(raw)
+}
+
+// migrate upgrades a manifest to the current version. Manifests newer than
+// the current version are left alone, so that the fields this version of
+// partner knows about can still be read.
+func migrate(b []byte) ([]byte, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	version, err := rawVersion(raw)
+	if err != nil {
+		return nil, err
+	}
+	if version >= CurrentVersion {
+		return b, nil
+	}
+	for ; version < CurrentVersion; version++ {
+		migration, ok := migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration from manifest version %d", version)
+		}
+		if err := migration(raw); err != nil {
+			return nil, fmt.Errorf("failed to migrate manifest from version %d: %w", version, err)
+		}
+	}
+	raw["version"] = json.RawMessage(fmt.Sprint(CurrentVersion))
+	return json.Marshal(raw)
+}
+
+func rawVersion(raw map[string]json.RawMessage) (int, error) {
+	v, ok := raw["version"]
+	if !ok {
+		return 1, nil
+	}
+	var version int
+	if err := json.Unmarshal(v, &version); err != nil {
+		return 0, fmt.Errorf("invalid manifest version %s", v)
+	}
+	if version < 1 {
+		return 0, fmt.Errorf("invalid manifest version %d", version)
+	}
+	return version, nil
+}
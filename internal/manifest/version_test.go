@@ -0,0 +1,79 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_Migrates(t *testing.T) {
+	version, err := FileVersion("testdata/manifest.json")
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+
+	m, err := Load("testdata/manifest.json")
+	require.NoError(t, err)
+	require.Equal(t, CurrentVersion, m.Version)
+	require.Len(t, m.Coauthors, 3)
+}
+
+func TestLoad_InvalidVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, version := range []string{"0", "-1"} {
+		path := filepath.Join(dir, "manifest.json")
+		err := ioutil.WriteFile(path, []byte(`{"version": `+version+`, "coauthors": {}}`), 0644)
+		require.NoError(t, err)
+		_, err = Load(path)
+		require.EqualError(t, err, path+": invalid manifest version "+version)
+	}
+}
+
+func TestLoad_NewerVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Known fields of a newer manifest can still be read
+	m, err := Load("testdata/newer.json")
+	require.NoError(t, err)
+	require.Equal(t, 99, m.Version)
+	require.Equal(t, "Brett Buddin", m.Coauthors["brettbuddin"].Name)
+
+	// but writing it back would lose the rest
+	err = WriteFile(filepath.Join(dir, "manifest.json"), m)
+	require.Equal(t, NewerVersionError{Path: filepath.Join(dir, "manifest.json"), Version: 99}, err)
+
+	b, err := ioutil.ReadFile("testdata/newer.json")
+	require.NoError(t, err)
+	newer := filepath.Join(dir, "newer.json")
+	err = ioutil.WriteFile(newer, b, 0644)
+	require.NoError(t, err)
+	err = WriteFile(newer, &Manifest{})
+	require.Equal(t, NewerVersionError{Path: newer, Version: 99}, err)
+
+	after, err := ioutil.ReadFile(newer)
+	require.NoError(t, err)
+	require.Equal(t, b, after)
+}
+
+func TestWriteFile_Version(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	m, err := Load("testdata/manifest.json")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "manifest.json")
+	err = WriteFile(path, m)
+	require.NoError(t, err)
+
+	version, err := FileVersion(path)
+	require.NoError(t, err)
+	require.Equal(t, CurrentVersion, version)
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Commit holds what is needed to recreate a commit
+type Commit struct {
+	Hash        string
+	Tree        string
+	Parents     []string
+	AuthorName  string
+	AuthorEmail string
+	// AuthorDate is in git's internal "<timestamp> <offset>" format.
+	AuthorDate string
+	Message    string
+}
+
+// ReadCommit reads a commit
+func ReadCommit(dir, rev string) (Commit, error) {
+	out, err := git(dir, "show", "-s", "--date=raw", "--format=%H%x00%T%x00%P%x00%an%x00%ae%x00%ad%x00%B", rev, "--")
+	if err != nil {
+		return Commit{}, err
+	}
+	fields := strings.SplitN(out, "\x00", 7)
+	if len(fields) != 7 {
+		return Commit{}, fmt.Errorf("unexpected output reading commit %s", rev)
+	}
+	return Commit{
+		Hash:        fields[0],
+		Tree:        fields[1],
+		Parents:     strings.Fields(fields[2]),
+		AuthorName:  fields[3],
+		AuthorEmail: fields[4],
+		AuthorDate:  fields[5],
+		Message:     fields[6] + "\n",
+	}, nil
+}
+
+// WriteCommit creates a commit object from c and returns its hash. The author
+// and author date of c are kept, while the current user becomes the
+// committer. No references are updated.
+func WriteCommit(dir string, c Commit) (string, error) {
+	args := []string{"commit-tree", c.Tree}
+	for _, p := range c.Parents {
+		args = append(args, "-p", p)
+	}
+	env := []string{
+		"GIT_AUTHOR_NAME=" + c.AuthorName,
+		"GIT_AUTHOR_EMAIL=" + c.AuthorEmail,
+		"GIT_AUTHOR_DATE=" + c.AuthorDate,
+	}
+	hash, err := gitInput(dir, strings.NewReader(c.Message), env, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to write commit: %w", err)
+	}
+	return hash, nil
+}
+
+// UpdateRef points ref at newHash, provided it still points at oldHash
+func UpdateRef(dir, ref, newHash, oldHash, reason string) error {
+	if _, err := git(dir, "update-ref", "-m", reason, ref, newHash, oldHash); err != nil {
+		return fmt.Errorf("failed to update %s: %w", ref, err)
+	}
+	return nil
+}
+
+// RemoteRefsContaining returns the remote-tracking references that contain
+// rev, which is to say the remotes it has been pushed to.
+func RemoteRefsContaining(dir, rev string) ([]string, error) {
+	out, err := git(dir, "for-each-ref", "--format=%(refname:short)", "--contains", rev, "refs/remotes")
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// RevList returns the commits selected by git rev-list arguments, oldest
+// first.
+func RevList(dir string, args ...string) ([]string, error) {
+	out, err := git(dir, append([]string{"rev-list", "--reverse", "--topo-order"}, args...)...)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// FullRefName returns the full name of the reference rev refers to, such as
+// refs/heads/main or HEAD. An empty name is returned if rev is not a
+// reference.
+func FullRefName(dir, rev string) (string, error) {
+	return git(dir, "rev-parse", "--symbolic-full-name", rev)
+}
+
+// Log returns the output of git log for each selected commit, formatted
+// according to format. See PRETTY FORMATS in git-log(1).
+func Log(dir, format string, args ...string) ([]string, error) {
+	out, err := git(dir, append([]string{"log", "-z", "--format=" + format}, args...)...)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\x00"), nil
+}
@@ -2,23 +2,108 @@ package repository
 
 import (
 	"fmt"
-	"os/exec"
+	"path/filepath"
+
+	"github.com/atrox/homedir"
+)
+
+// Scope selects which git configuration file is modified
+type Scope string
+
+const (
+	// ScopeLocal applies to the repository and all of its worktrees
+	ScopeLocal Scope = "local"
+	// ScopeWorktree applies to a single worktree
+	ScopeWorktree Scope = "worktree"
+	// ScopeGlobal applies to every repository of the user
+	ScopeGlobal Scope = "global"
 )
 
-func SetCommitTemplate(dir string, templatePath string) error {
-	cmd := exec.Command("git", "config", "commit.template", templatePath)
-	cmd.Dir = dir
-	if err := cmd.Run(); err != nil {
+// previousTemplateKey records the commit.template value that partner replaced
+const previousTemplateKey = "partner.previousTemplate"
+
+// SetCommitTemplate registers templatePath as the commit template. Any other
+// template configured in the same scope is remembered so that it can be put
+// back by UnsetCommitTemplate.
+func SetCommitTemplate(dir string, scope Scope, templatePath string) error {
+	if scope == ScopeWorktree {
+		// Worktree specific configuration is ignored unless this extension is
+		// enabled for the repository.
+		if err := SetConfig(dir, ScopeLocal, "extensions.worktreeConfig", "true"); err != nil {
+			return fmt.Errorf("failed to enable worktree configuration: %w", err)
+		}
+	}
+
+	prev, ok, err := ScopedConfig(dir, scope, "commit.template")
+	if err != nil {
+		return err
+	}
+	if ok && prev != templatePath {
+		if err := SetConfig(dir, scope, previousTemplateKey, prev); err != nil {
+			return fmt.Errorf("failed to record previous commit template: %w", err)
+		}
+	}
+
+	if err := SetConfig(dir, scope, "commit.template", templatePath); err != nil {
 		return fmt.Errorf("failed to set commit template: %w", err)
 	}
 	return nil
 }
 
-func UnsetCommitTemplate(dir string) error {
-	cmd := exec.Command("git", "config", "--unset", "commit.template")
-	cmd.Dir = dir
-	if err := cmd.Run(); err != nil {
+// UnsetCommitTemplate unregisters partner's commit template, restoring the
+// template it replaced, if any.
+func UnsetCommitTemplate(dir string, scope Scope) error {
+	prev, ok, err := ScopedConfig(dir, scope, previousTemplateKey)
+	if err != nil {
+		return err
+	}
+	if ok {
+		if err := SetConfig(dir, scope, "commit.template", prev); err != nil {
+			return fmt.Errorf("failed to restore commit template: %w", err)
+		}
+		return UnsetConfig(dir, scope, previousTemplateKey)
+	}
+
+	if err := UnsetConfig(dir, scope, "commit.template"); err != nil {
 		return fmt.Errorf("failed to unset commit template: %w", err)
 	}
 	return nil
 }
+
+// BaseCommitTemplate returns the path of the commit template that partner's
+// template should build upon: either the template partner replaced, or one
+// inherited from a broader configuration scope. Templates managed by partner
+// are never returned. An empty path is returned if there is no such template.
+func BaseCommitTemplate(dir string, scope Scope, managed ...string) (string, error) {
+	path, ok, err := ScopedConfig(dir, scope, previousTemplateKey)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		if scope == ScopeGlobal {
+			path, ok, err = ScopedConfig(dir, scope, "commit.template")
+		} else {
+			path, ok, err = Config(dir, "commit.template")
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	if !ok {
+		return "", nil
+	}
+	for _, m := range managed {
+		if path == m {
+			return "", nil
+		}
+	}
+
+	path, err = homedir.Expand(path)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path, nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"errors"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// Config returns the value of a git configuration key. The boolean reports
+// whether the key is set at all.
+func Config(dir string, key string) (string, bool, error) {
+	return getConfig(dir, "--get", key)
+}
+
+// ConfigAll returns every value of a multi-valued git configuration key
+func ConfigAll(dir string, key string) ([]string, error) {
+	v, ok, err := getConfig(dir, "--get-all", key)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return strings.Split(v, "\n"), nil
+}
+
+// ConfigMatching returns the values of all git configuration keys matching a
+// regular expression, indexed by key. Section and variable names in the keys
+// are lowercased by git; subsection names keep their case.
+func ConfigMatching(dir string, pattern string) (map[string]string, error) {
+	v, ok, err := getConfig(dir, "--get-regexp", pattern)
+	if err != nil || !ok {
+		return nil, err
+	}
+	values := map[string]string{}
+	for _, line := range strings.Split(v, "\n") {
+		kv := strings.SplitN(line, " ", 2)
+		if len(kv) == 2 {
+			values[kv[0]] = kv[1]
+		} else {
+			values[kv[0]] = ""
+		}
+	}
+	return values, nil
+}
+
+// ScopedConfig returns the value of a git configuration key as set in a
+// specific scope, ignoring values inherited from other scopes.
+func ScopedConfig(dir string, scope Scope, key string) (string, bool, error) {
+	if scope == ScopeWorktree {
+		// git refuses to read worktree configuration in repositories with
+		// several worktrees until it has been enabled.
+		enabled, err := ConfigBool(dir, "extensions.worktreeConfig")
+		if err != nil || !enabled {
+			return "", false, err
+		}
+	}
+	return getConfig(dir, "--"+string(scope), "--get", key)
+}
+
+// ConfigBool returns the value of a boolean git configuration key. Unset keys
+// are false.
+func ConfigBool(dir string, key string) (bool, error) {
+	v, _, err := getConfig(dir, "--type=bool", "--get", key)
+	if err != nil {
+		return false, err
+	}
+	return v == "true", nil
+}
+
+// SetConfig sets a git configuration key
+func SetConfig(dir string, scope Scope, key, value string) error {
+	_, err := git(dir, "config", "--"+string(scope), key, value)
+	return err
+}
+
+// AddConfig adds a value to a multi-valued git configuration key
+func AddConfig(dir string, scope Scope, key, value string) error {
+	_, err := git(dir, "config", "--"+string(scope), "--add", key, value)
+	return err
+}
+
+// UnsetConfig removes a git configuration key
+func UnsetConfig(dir string, scope Scope, key string) error {
+	_, err := git(dir, "config", "--"+string(scope), "--unset", key)
+	return err
+}
+
+// UnsetConfigValue removes a single value from a multi-valued git
+// configuration key
+func UnsetConfigValue(dir string, scope Scope, key, value string) error {
+	_, err := git(dir, "config", "--"+string(scope), "--unset-all", key, "^"+regexp.QuoteMeta(value)+"$")
+	return err
+}
+
+func getConfig(dir string, args ...string) (string, bool, error) {
+	cmd := exec.Command("git", append([]string{"config"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		// git config exits with status 1 when the key is not set.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return strings.TrimSpace(string(out)), true, nil
+}
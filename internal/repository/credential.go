@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"strings"
+)
+
+// Credential returns the username and password git's credential helpers have
+// stored for an https host. Nothing is ever prompted for: when no helper has a
+// credential, an empty password is returned. Prompts are disabled both in git
+// itself and in Git Credential Manager, which would otherwise open a sign-in
+// window.
+func Credential(dir, host string) (username, password string) {
+	input := "protocol=https\nhost=" + host + "\n\n"
+	env := []string{"GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=", "GCM_INTERACTIVE=never"}
+	out, err := gitInput(dir, strings.NewReader(input), env, "credential", "fill")
+	if err != nil {
+		// git fails once it would have to prompt.
+		return "", ""
+	}
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "username="):
+			username = strings.TrimPrefix(line, "username=")
+		case strings.HasPrefix(line, "password="):
+			password = strings.TrimPrefix(line, "password=")
+		}
+	}
+	return username, password
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// HookMarker identifies hook scripts written by partner
+const HookMarker = "# Managed by partner"
+
+// LegacyHookSuffix is appended to the name of a hook that existed before
+// partner installed its own. Partner's hook scripts run the legacy hook first.
+const LegacyHookSuffix = ".partner-legacy"
+
+// HookStatus describes the state of a single hook
+type HookStatus struct {
+	Name string
+	Path string
+	// Installed reports whether partner's hook script is in place.
+	Installed bool
+	// Foreign reports whether a hook not managed by partner is in place.
+	Foreign bool
+	// Legacy is the path of a pre-existing hook that partner's hook chains
+	// to, if any.
+	Legacy string
+}
+
+// HooksDir returns the directory git looks in for hooks. This honors
+// core.hooksPath and linked worktrees.
+func HooksDir(dir string) (string, error) {
+	path, err := git(dir, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path, nil
+}
+
+// Hook reports the state of a hook
+func Hook(dir, name string) (HookStatus, error) {
+	hooksDir, err := HooksDir(dir)
+	if err != nil {
+		return HookStatus{}, err
+	}
+	status := HookStatus{
+		Name: name,
+		Path: filepath.Join(hooksDir, name),
+	}
+
+	b, err := ioutil.ReadFile(status.Path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return HookStatus{}, fmt.Errorf("failed to read %s hook: %w", name, err)
+	case bytes.Contains(b, []byte(HookMarker)):
+		status.Installed = true
+	default:
+		status.Foreign = true
+	}
+
+	legacy := status.Path + LegacyHookSuffix
+	if _, err := os.Stat(legacy); err == nil {
+		status.Legacy = legacy
+	}
+	return status, nil
+}
+
+// InstallHook writes an executable hook script into the repository's hooks
+// directory. A hook that was not written by partner is moved aside so that
+// the script can chain to it.
+func InstallHook(dir, name, script string) error {
+	status, err := Hook(dir, name)
+	if err != nil {
+		return err
+	}
+	if status.Foreign {
+		if status.Legacy != "" {
+			return fmt.Errorf("cannot install %s hook: both %s and %s already exist", name, status.Path, status.Legacy)
+		}
+		if err := os.Rename(status.Path, status.Path+LegacyHookSuffix); err != nil {
+			return fmt.Errorf("failed to move existing %s hook aside: %w", name, err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(status.Path), os.ModePerm); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(status.Path, []byte(script), 0755); err != nil {
+		return fmt.Errorf("failed to write %s hook: %w", name, err)
+	}
+	return os.Chmod(status.Path, 0755)
+}
+
+// UninstallHook removes partner's hook script and restores any hook that it
+// was chained to.
+func UninstallHook(dir, name string) error {
+	status, err := Hook(dir, name)
+	if err != nil {
+		return err
+	}
+	if !status.Installed {
+		return nil
+	}
+	if err := os.Remove(status.Path); err != nil {
+		return fmt.Errorf("failed to remove %s hook: %w", name, err)
+	}
+	if status.Legacy != "" {
+		if err := os.Rename(status.Legacy, status.Path); err != nil {
+			return fmt.Errorf("failed to restore previous %s hook: %w", name, err)
+		}
+	}
+	return nil
+}
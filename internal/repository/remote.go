@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"net/url"
+	"strings"
+)
+
+// RemoteHost returns the host of the remote the repository containing dir
+// pushes to: the remote of the current branch's upstream, origin, or
+// otherwise the first remote. An empty host is returned if the repository has
+// no remotes.
+func RemoteHost(dir string) (string, error) {
+	remote, err := defaultRemote(dir)
+	if err != nil || remote == "" {
+		return "", err
+	}
+	u, _, err := Config(dir, "remote."+remote+".url")
+	if err != nil {
+		return "", err
+	}
+	return URLHost(u), nil
+}
+
+func defaultRemote(dir string) (string, error) {
+	branch, err := CurrentBranch(dir)
+	if err != nil {
+		return "", err
+	}
+	if branch != "" {
+		remote, ok, err := Config(dir, "branch."+branch+".remote")
+		if err != nil {
+			return "", err
+		}
+		if ok && remote != "." {
+			return remote, nil
+		}
+	}
+
+	out, err := git(dir, "remote")
+	if err != nil || out == "" {
+		return "", err
+	}
+	remotes := strings.Split(out, "\n")
+	for _, remote := range remotes {
+		if remote == "origin" {
+			return remote, nil
+		}
+	}
+	return remotes[0], nil
+}
+
+// URLHost returns the host name of a git remote URL. Both URLs with a scheme
+// and scp-like addresses (git@host:path) are understood. Local paths have no
+// host.
+func URLHost(remoteURL string) string {
+	if strings.Contains(remoteURL, "://") {
+		u, err := url.Parse(remoteURL)
+		if err != nil {
+			return ""
+		}
+		return strings.ToLower(u.Hostname())
+	}
+	// scp-like syntax is only recognized by git when there is no slash before
+	// the first colon.
+	colon := strings.Index(remoteURL, ":")
+	if colon < 0 || strings.Contains(remoteURL[:colon], "/") {
+		return ""
+	}
+	host := remoteURL[:colon]
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		host = host[at+1:]
+	}
+	return strings.ToLower(host)
+}
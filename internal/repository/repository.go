@@ -2,22 +2,81 @@ package repository
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
 func Root(pwd string) (string, error) {
+	return git(pwd, "rev-parse", "--show-toplevel")
+}
+
+// git runs a git subcommand in dir and returns its trimmed output. Failures
+// are reported using git's own error message.
+func git(dir string, args ...string) (string, error) {
+	return gitInput(dir, nil, nil, args...)
+}
+
+// gitInput runs a git subcommand in dir, with stdin and additional
+// environment variables.
+func gitInput(dir string, stdin io.Reader, env []string, args ...string) (string, error) {
 	var (
 		stdout = bytes.NewBuffer(nil)
 		stderr = bytes.NewBuffer(nil)
 	)
-	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
-	cmd.Dir = pwd
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = stdin
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 	if err := cmd.Run(); err != nil {
 		return "", fmt.Errorf(strings.TrimSpace(stderr.String()))
 	}
 	return strings.TrimSpace(stdout.String()), nil
 }
+
+// GitDir returns the absolute path of the git directory for the worktree
+// containing dir. For linked worktrees and submodules this is not the .git
+// entry at the root of the checkout.
+func GitDir(dir string) (string, error) {
+	return git(dir, "rev-parse", "--absolute-git-dir")
+}
+
+// CommonDir returns the absolute path of the git directory shared by all
+// worktrees of the repository containing dir.
+func CommonDir(dir string) (string, error) {
+	path, err := git(dir, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path, nil
+}
+
+// CurrentBranch returns the short name of the branch checked out in dir. An
+// empty name is returned when HEAD is detached.
+func CurrentBranch(dir string) (string, error) {
+	cmd := exec.Command("git", "symbolic-ref", "--quiet", "HEAD")
+	cmd.Dir = dir
+	stderr := bytes.NewBuffer(nil)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// symbolic-ref --quiet exits with status 1 when HEAD is detached.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", nil
+		}
+		return "", fmt.Errorf(strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(out)), "refs/heads/"), nil
+}
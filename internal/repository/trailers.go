@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// InterpretTrailers runs git interpret-trailers over a commit message and
+// returns its output verbatim. The trailer configuration of the repository and
+// user applies.
+func InterpretTrailers(dir string, message []byte, args ...string) ([]byte, error) {
+	var (
+		stdout = bytes.NewBuffer(nil)
+		stderr = bytes.NewBuffer(nil)
+	)
+	cmd := exec.Command("git", append([]string{"interpret-trailers"}, args...)...)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(message)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git interpret-trailers: %s", strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
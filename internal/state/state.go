@@ -0,0 +1,60 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/brettbuddin/partner/internal/lockedfile"
+)
+
+// State records an activation of coauthors
+type State struct {
+	// Version of partner that wrote the state
+	Version     string     `json:"version"`
+	Scope       string     `json:"scope"`
+	IDs         []string   `json:"ids"`
+	ActivatedAt time.Time  `json:"activated_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// Expired reports whether the coauthors should no longer be credited at t
+func (s State) Expired(t time.Time) bool {
+	return s.ExpiresAt != nil && !t.Before(*s.ExpiresAt)
+}
+
+// Load reads a State. A nil State is returned if the file does not exist.
+func Load(path string) (*State, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var s State
+	if err := json.NewDecoder(f).Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// WriteFile saves the state to a JSON file
+func WriteFile(path string, s *State) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return lockedfile.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// Remove deletes a state file. It is not an error if the file does not exist.
+func Remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_Missing(t *testing.T) {
+	s, err := Load("testdata/missing.json")
+	require.NoError(t, err)
+	require.Nil(t, s)
+}
+
+func TestSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	expires := time.Date(2021, 1, 4, 13, 0, 0, 0, time.UTC)
+	s := &State{
+		Version:     "v0.1.0",
+		Scope:       "local",
+		IDs:         []string{"persona", "personb"},
+		ActivatedAt: time.Date(2021, 1, 4, 9, 0, 0, 0, time.UTC),
+		ExpiresAt:   &expires,
+	}
+
+	path := filepath.Join(dir, "partner/state.json")
+	err = WriteFile(path, s)
+	require.NoError(t, err)
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	require.Equal(t, s, loaded)
+
+	require.False(t, loaded.Expired(expires.Add(-time.Second)))
+	require.True(t, loaded.Expired(expires))
+
+	err = Remove(path)
+	require.NoError(t, err)
+	err = Remove(path)
+	require.NoError(t, err)
+}
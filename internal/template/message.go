@@ -0,0 +1,41 @@
+package template
+
+import (
+	"strings"
+
+	"github.com/brettbuddin/partner/internal/trailer"
+)
+
+// ManagedIDs returns the IDs of coauthors in the block a partner commit
+// template contributed to a commit message.
+func ManagedIDs(message []byte) []string {
+	return extractIDs(message)
+}
+
+// StripManaged removes the block a partner commit template contributed to a
+// commit message, including its trailers.
+func StripManaged(message []byte) []byte {
+	var (
+		lines = strings.Split(string(message), "\n")
+		out   []string
+	)
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+		switch {
+		case l == managedHeader:
+			if i+1 < len(lines) && expiresPattern.MatchString(lines[i+1]) {
+				i++
+			}
+			if i+1 < len(lines) && lines[i+1] == "#" {
+				i++
+			}
+		case extractPattern.MatchString(l):
+			if i+1 < len(lines) && strings.HasPrefix(lines[i+1], trailer.CoAuthoredBy+":") {
+				i++
+			}
+		default:
+			out = append(out, l)
+		}
+	}
+	return []byte(strings.Join(out, "\n"))
+}
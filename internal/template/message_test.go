@@ -0,0 +1,29 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripManaged(t *testing.T) {
+	msg := `Fix the thing
+
+# Managed by partner
+# partner-expires: 2021-01-04T13:00:00Z
+#
+# partner-id: persona
+Co-Authored-By: Person A <a@buddin.org>
+# partner-id: personb
+Co-Authored-By: Person B <b@buddin.org>
+Signed-off-by: Brett Buddin <brett@buddin.org>
+`
+	require.Equal(t, []string{"persona", "personb"}, ManagedIDs([]byte(msg)))
+
+	out := StripManaged([]byte(msg))
+	require.Equal(t, `Fix the thing
+
+Signed-off-by: Brett Buddin <brett@buddin.org>
+`, string(out))
+	require.Empty(t, ManagedIDs(out))
+}
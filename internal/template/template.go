@@ -7,13 +7,23 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
+	"github.com/brettbuddin/partner/internal/lockedfile"
 	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/brettbuddin/partner/internal/trailer"
 )
 
-const coAuthoredBy = "Co-Authored-By"
+// DefaultGlobalPath is where the commit template for globally activated
+// coauthors is kept
+const DefaultGlobalPath = "~/.config/partner/gitmessage.txt"
 
-var extractPattern = regexp.MustCompile("# partner-id: (.+)")
+const managedHeader = "# Managed by partner"
+
+var (
+	extractPattern = regexp.MustCompile("# partner-id: (.+)")
+	expiresPattern = regexp.MustCompile("# partner-expires: (.+)")
+)
 
 // ExtractIDs returns the IDs of coauthors referenced in the git commit template
 // file.
@@ -26,43 +36,73 @@ func ExtractIDs(path string) ([]string, error) {
 		return nil, err
 	}
 
+	return extractIDs(b), nil
+}
+
+// ExtractExpiry returns the time after which the coauthors in the git commit
+// template file should no longer be credited. The zero time is returned if
+// they never expire.
+func ExtractExpiry(path string) (time.Time, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+
+	m := expiresPattern.FindSubmatch(b)
+	if m == nil {
+		return time.Time{}, nil
+	}
+	expires, err := time.Parse(time.RFC3339, string(m[1]))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expiry in commit template: %w", err)
+	}
+	return expires, nil
+}
+
+func extractIDs(b []byte) []string {
 	var usernames []string
 	matches := extractPattern.FindAllSubmatch(b, -1)
 	for _, m := range matches {
 		usernames = append(usernames, string(m[1]))
 	}
-	return usernames, nil
+	return usernames
 }
 
 // Template is a git commit template containing a list of coauthors
 type Template struct {
+	// Base is the content of a commit template that the coauthors are
+	// appended to.
+	Base      string
 	Coauthors []manifest.Coauthor
+	// Expires is when the coauthors should no longer be credited. The zero
+	// time means never.
+	Expires time.Time
 }
 
 func (t Template) trailers() string {
 	var b strings.Builder
-	b.WriteString("\n\n# Managed by partner\n#\n")
+	b.WriteString("\n\n" + managedHeader + "\n")
+	if !t.Expires.IsZero() {
+		fmt.Fprintf(&b, "# partner-expires: %s\n", t.Expires.UTC().Format(time.RFC3339))
+	}
+	b.WriteString("#\n")
 	for _, ca := range t.Coauthors {
-		fmt.Fprintf(&b, "# partner-id: %s\n%s: %q <%s>\n", ca.ID, coAuthoredBy, ca.Name, ca.Email)
+		fmt.Fprintf(&b, "# partner-id: %s\n%s\n", ca.ID, trailer.Coauthor(ca))
 	}
 	return b.String()
 }
 
 // WriteFile saves and registers the git commit template
 func WriteFile(path string, t Template) error {
-	f, err := os.Create(path)
-	if err != nil {
-		return fmt.Errorf("failed to open commit template: %w", err)
+	var content string
+	if len(t.Coauthors) > 0 {
+		content = strings.TrimRight(t.Base, "\n") + t.trailers()
 	}
-	defer f.Close()
-
-	if len(t.Coauthors) == 0 {
-		f.Truncate(0)
-		return nil
-	}
-
-	if _, err := f.WriteString(t.trailers()); err != nil {
-		return fmt.Errorf("failed to write to commit template file: %w", err)
+	if err := lockedfile.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write commit template: %w", err)
 	}
 	return nil
 }
@@ -5,13 +5,19 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/brettbuddin/partner/internal/manifest"
 	"github.com/stretchr/testify/require"
 )
 
 func TestActiveCoauthors(t *testing.T) {
-	ids, err := ExtractIDs("testdata/gitmessage.txt")
+	ids, err := ExtractIDs("testdata/gitmessage_trailers.txt")
+	require.NoError(t, err)
+	require.Equal(t, []string{"persona", "personb"}, ids)
+
+	// Templates written before trailers were rendered by git quote names
+	ids, err = ExtractIDs("testdata/gitmessage.txt")
 	require.NoError(t, err)
 	require.Equal(t, []string{"persona", "personb"}, ids)
 }
@@ -42,9 +48,70 @@ func TestSave(t *testing.T) {
 	err = WriteFile(path, tmpl)
 	require.NoError(t, err)
 
-	expected, err := ioutil.ReadFile("testdata/gitmessage.txt")
+	expected, err := ioutil.ReadFile("testdata/gitmessage_trailers.txt")
 	require.NoError(t, err)
 	actual, err := ioutil.ReadFile(path)
 	require.NoError(t, err)
 	require.Equal(t, expected, actual)
 }
+
+func TestSave_Base(t *testing.T) {
+	dir, err := ioutil.TempDir("", "template")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tmpl := Template{
+		Base: "feat: \n\n# What changed and why?\n",
+		Coauthors: []manifest.Coauthor{
+			{
+				ID:    "persona",
+				Name:  "Person A",
+				Type:  manifest.CoauthorTypeManual,
+				Email: "a@buddin.org",
+			},
+		},
+	}
+
+	path := filepath.Join(dir, "gitmessage.txt")
+	err = WriteFile(path, tmpl)
+	require.NoError(t, err)
+
+	actual, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, `feat: 
+
+# What changed and why?
+
+# Managed by partner
+#
+# partner-id: persona
+Co-Authored-By: Person A <a@buddin.org>
+`, string(actual))
+}
+
+func TestExtractExpiry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "template")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	expires := time.Date(2021, 1, 4, 13, 0, 0, 0, time.UTC)
+	path := filepath.Join(dir, "gitmessage.txt")
+	err = WriteFile(path, Template{
+		Coauthors: []manifest.Coauthor{{ID: "persona", Name: "Person A", Email: "a@buddin.org"}},
+		Expires:   expires,
+	})
+	require.NoError(t, err)
+
+	actual, err := ExtractExpiry(path)
+	require.NoError(t, err)
+	require.True(t, expires.Equal(actual))
+
+	ids, err := ExtractIDs(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"persona"}, ids)
+
+	// Templates without an expiry never expire
+	actual, err = ExtractExpiry("testdata/gitmessage_trailers.txt")
+	require.NoError(t, err)
+	require.True(t, actual.IsZero())
+}
@@ -0,0 +1,176 @@
+package trailer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/brettbuddin/partner/internal/repository"
+)
+
+// CoAuthoredBy is the key of trailers crediting coauthors
+const CoAuthoredBy = "Co-Authored-By"
+
+// Trailer is a "key: value" line at the end of a commit message
+type Trailer struct {
+	Key   string
+	Value string
+}
+
+func (t Trailer) String() string {
+	return t.Key + ": " + t.Value
+}
+
+// Coauthor returns the trailer crediting a coauthor
+func Coauthor(ca manifest.Coauthor) Trailer {
+	return Trailer{Key: CoAuthoredBy, Value: fmt.Sprintf("%s <%s>", ca.Name, ca.Email)}
+}
+
+// IfExists is the action taken when a message already has a trailer with the
+// same key as one being added. See trailer.ifExists in git-interpret-trailers(1).
+type IfExists string
+
+// IfExists policies
+const (
+	AddIfDifferentNeighbor IfExists = "addIfDifferentNeighbor"
+	AddIfDifferent         IfExists = "addIfDifferent"
+	Add                    IfExists = "add"
+	Replace                IfExists = "replace"
+	DoNothing              IfExists = "doNothing"
+)
+
+// Parse returns the trailers of a commit message, as git understands them.
+// Continuation lines are unfolded, and keys are normalized according to any
+// trailer.<token>.key configuration.
+func Parse(dir string, message []byte) ([]Trailer, error) {
+	out, err := repository.InterpretTrailers(dir, message, "--parse")
+	if err != nil {
+		return nil, err
+	}
+	separators, err := separators(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var trailers []Trailer
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		i := strings.IndexAny(line, separators)
+		if i < 0 {
+			continue
+		}
+		trailers = append(trailers, Trailer{
+			Key:   strings.TrimSpace(line[:i]),
+			Value: strings.TrimSpace(line[i+1:]),
+		})
+	}
+	return trailers, nil
+}
+
+// Append adds trailers to a commit message. They are placed the way git places
+// them: after any existing trailers and ahead of comments and scissors lines.
+func Append(dir string, message []byte, ifExists IfExists, trailers ...Trailer) ([]byte, error) {
+	if len(trailers) == 0 {
+		return message, nil
+	}
+
+	args := []string{"--if-exists", string(ifExists)}
+	for _, t := range trailers {
+		args = append(args, "--trailer", t.String())
+	}
+	out, err := repository.InterpretTrailers(dir, message, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// git leaves a single blank line ahead of trailers added to an empty
+	// message. Leave room for a subject line as well, so that what gets typed
+	// in an editor isn't taken for part of the trailer block.
+	empty, err := isEmpty(dir, message)
+	if err != nil {
+		return nil, err
+	}
+	if empty {
+		out = append([]byte("\n"), out...)
+	}
+	return out, nil
+}
+
+// Uncredited returns the coauthors not yet credited in a Co-Authored-By
+// trailer of a commit message, under any of their email addresses.
+func Uncredited(dir string, message []byte, coauthors []manifest.Coauthor) ([]manifest.Coauthor, error) {
+	trailers, err := Parse(dir, message)
+	if err != nil {
+		return nil, err
+	}
+
+	credited := map[string]bool{}
+	for _, t := range trailers {
+		if !strings.EqualFold(t.Key, CoAuthoredBy) {
+			continue
+		}
+		if i := strings.LastIndex(t.Value, "<"); i >= 0 {
+			email := strings.TrimSuffix(t.Value[i+1:], ">")
+			credited[strings.ToLower(email)] = true
+		}
+	}
+
+	var out []manifest.Coauthor
+	for _, ca := range coauthors {
+		if !creditedAny(credited, ca.Emails()) {
+			out = append(out, ca)
+		}
+	}
+	return out, nil
+}
+
+func creditedAny(credited map[string]bool, emails []string) bool {
+	for _, email := range emails {
+		if credited[strings.ToLower(email)] {
+			return true
+		}
+	}
+	return false
+}
+
+// AppendCoauthors adds Co-Authored-By trailers to a commit message for the
+// coauthors it doesn't already credit.
+func AppendCoauthors(dir string, message []byte, coauthors []manifest.Coauthor) ([]byte, error) {
+	missing, err := Uncredited(dir, message, coauthors)
+	if err != nil {
+		return nil, err
+	}
+	var trailers []Trailer
+	for _, ca := range missing {
+		trailers = append(trailers, Coauthor(ca))
+	}
+	return Append(dir, message, AddIfDifferent, trailers...)
+}
+
+// separators returns the characters git accepts between a trailer's key and
+// value
+func separators(dir string) (string, error) {
+	v, ok, err := repository.Config(dir, "trailer.separators")
+	if err != nil || !ok {
+		return ":", err
+	}
+	return v, nil
+}
+
+// isEmpty reports whether a commit message has nothing but blank lines and
+// comments
+func isEmpty(dir string, message []byte) (bool, error) {
+	comment, ok, err := repository.Config(dir, "core.commentChar")
+	if err != nil {
+		return false, err
+	}
+	if !ok || comment == "auto" || comment == "" {
+		comment = "#"
+	}
+	for _, line := range strings.Split(string(message), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, comment) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,300 @@
+package trailer
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/brettbuddin/partner/internal/manifest"
+	"github.com/stretchr/testify/require"
+)
+
+// newRepository creates an empty repository with the given local config, so
+// that each test sees git's default trailer rules plus its own.
+func newRepository(t *testing.T, config ...string) string {
+	dir, err := ioutil.TempDir("", "trailer")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	run := func(args ...string) {
+		git := exec.Command("git", args...)
+		git.Dir = dir
+		out, err := git.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	run("init", "-q")
+	for i := 0; i+1 < len(config); i += 2 {
+		run("config", config[i], config[i+1])
+	}
+	return dir
+}
+
+var coauthors = []manifest.Coauthor{
+	{ID: "persona", Name: "Person A", Email: "a@buddin.org"},
+	{ID: "personb", Name: "Person B", Email: "b@buddin.org"},
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   []string
+		message  string
+		expected []Trailer
+	}{
+		{
+			name:    "no trailers",
+			message: "Fix the thing\n\nIt was broken.\n",
+		},
+		{
+			name: "trailer block",
+			message: `Fix the thing
+
+Signed-off-by: Brett Buddin <brett@buddin.org>
+Co-Authored-By: Person A <a@buddin.org>
+`,
+			expected: []Trailer{
+				{Key: "Signed-off-by", Value: "Brett Buddin <brett@buddin.org>"},
+				{Key: CoAuthoredBy, Value: "Person A <a@buddin.org>"},
+			},
+		},
+		{
+			name: "continuation lines",
+			message: `Fix the thing
+
+Co-Authored-By: Person A
+  <a@buddin.org>
+`,
+			expected: []Trailer{
+				{Key: CoAuthoredBy, Value: "Person A <a@buddin.org>"},
+			},
+		},
+		{
+			name: "comments in trailer block",
+			message: `Fix the thing
+
+Co-Authored-By: Person A <a@buddin.org>
+# partner-id: personb
+Co-Authored-By: Person B <b@buddin.org>
+`,
+			expected: []Trailer{
+				{Key: CoAuthoredBy, Value: "Person A <a@buddin.org>"},
+				{Key: CoAuthoredBy, Value: "Person B <b@buddin.org>"},
+			},
+		},
+		{
+			name:   "custom separators",
+			config: []string{"trailer.separators", ":="},
+			message: `Fix the thing
+
+Co-Authored-By = Person A <a@buddin.org>
+`,
+			expected: []Trailer{
+				{Key: CoAuthoredBy, Value: "Person A <a@buddin.org>"},
+			},
+		},
+		{
+			name:   "configured key",
+			config: []string{"trailer.coauthor.key", CoAuthoredBy},
+			message: `Fix the thing
+
+coauthor: Person A <a@buddin.org>
+`,
+			expected: []Trailer{
+				{Key: CoAuthoredBy, Value: "Person A <a@buddin.org>"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newRepository(t, tt.config...)
+			trailers, err := Parse(dir, []byte(tt.message))
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, trailers)
+		})
+	}
+}
+
+func TestAppend(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   []string
+		ifExists IfExists
+		message  string
+		expected string
+	}{
+		{
+			name:    "subject only",
+			message: "Fix the thing\n",
+			expected: `Fix the thing
+
+Co-Authored-By: Person A <a@buddin.org>
+`,
+		},
+		{
+			name:    "empty message",
+			message: "",
+			expected: `
+
+Co-Authored-By: Person A <a@buddin.org>
+`,
+		},
+		{
+			name:    "comments only",
+			message: "\n# Managed by partner\n",
+			expected: `
+
+Co-Authored-By: Person A <a@buddin.org>
+# Managed by partner
+`,
+		},
+		{
+			name: "existing trailer block",
+			message: `Fix the thing
+
+Signed-off-by: Brett Buddin <brett@buddin.org>
+`,
+			expected: `Fix the thing
+
+Signed-off-by: Brett Buddin <brett@buddin.org>
+Co-Authored-By: Person A <a@buddin.org>
+`,
+		},
+		{
+			name: "trailing comments and scissors",
+			message: `Fix the thing
+
+# Please enter the commit message for your changes.
+#
+# ------------------------ >8 ------------------------
+# Do not modify or remove the line above.
+diff --git a/file b/file
+`,
+			expected: `Fix the thing
+
+Co-Authored-By: Person A <a@buddin.org>
+
+# Please enter the commit message for your changes.
+#
+# ------------------------ >8 ------------------------
+# Do not modify or remove the line above.
+diff --git a/file b/file
+`,
+		},
+		{
+			name:   "comment character",
+			config: []string{"core.commentChar", ";"},
+			message: `Fix the thing
+
+; Please enter the commit message for your changes.
+`,
+			expected: `Fix the thing
+
+Co-Authored-By: Person A <a@buddin.org>
+
+; Please enter the commit message for your changes.
+`,
+		},
+		{
+			name:     "add if different",
+			ifExists: AddIfDifferent,
+			message: `Fix the thing
+
+Co-Authored-By: Person A <a@buddin.org>
+Signed-off-by: Brett Buddin <brett@buddin.org>
+`,
+			expected: `Fix the thing
+
+Co-Authored-By: Person A <a@buddin.org>
+Signed-off-by: Brett Buddin <brett@buddin.org>
+`,
+		},
+		{
+			name:     "add if different neighbor",
+			ifExists: AddIfDifferentNeighbor,
+			message: `Fix the thing
+
+Co-Authored-By: Person A <a@buddin.org>
+Signed-off-by: Brett Buddin <brett@buddin.org>
+`,
+			expected: `Fix the thing
+
+Co-Authored-By: Person A <a@buddin.org>
+Signed-off-by: Brett Buddin <brett@buddin.org>
+Co-Authored-By: Person A <a@buddin.org>
+`,
+		},
+		{
+			name:     "replace",
+			ifExists: Replace,
+			message: `Fix the thing
+
+Co-Authored-By: Person B <b@buddin.org>
+`,
+			expected: `Fix the thing
+
+Co-Authored-By: Person A <a@buddin.org>
+`,
+		},
+		{
+			name:     "do nothing",
+			ifExists: DoNothing,
+			message: `Fix the thing
+
+Co-Authored-By: Person B <b@buddin.org>
+`,
+			expected: `Fix the thing
+
+Co-Authored-By: Person B <b@buddin.org>
+`,
+		},
+		{
+			name:     "configured key",
+			config:   []string{"trailer.coauthor.key", CoAuthoredBy},
+			ifExists: AddIfDifferent,
+			message: `Fix the thing
+
+coauthor: Person A <a@buddin.org>
+`,
+			expected: `Fix the thing
+
+Co-Authored-By: Person A <a@buddin.org>
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newRepository(t, tt.config...)
+			ifExists := tt.ifExists
+			if ifExists == "" {
+				ifExists = Add
+			}
+			out, err := Append(dir, []byte(tt.message), ifExists, Coauthor(coauthors[0]))
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(out))
+		})
+	}
+}
+
+func TestAppendCoauthors(t *testing.T) {
+	dir := newRepository(t)
+
+	msg := `Fix the thing
+
+co-authored-by: Person A <A@buddin.org>
+`
+	out, err := AppendCoauthors(dir, []byte(msg), coauthors)
+	require.NoError(t, err)
+	require.Equal(t, `Fix the thing
+
+co-authored-by: Person A <A@buddin.org>
+Co-Authored-By: Person B <b@buddin.org>
+`, string(out))
+
+	// Already credited coauthors aren't added again
+	again, err := AppendCoauthors(dir, out, coauthors)
+	require.NoError(t, err)
+	require.Equal(t, string(out), string(again))
+}